@@ -0,0 +1,55 @@
+package slogsentry
+
+import (
+	"log/slog"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// metaKey is the key of the attribute returned by Meta.
+const metaKey = "sentry_meta"
+
+// meta is the value of the attribute returned by Meta.
+type meta struct {
+	level  sentry.Level
+	logger string
+}
+
+// Meta returns an attribute setting the level and logger of the Sentry event
+// of a record. An empty level keeps the level derived from the record, an
+// empty logger sets none.
+func Meta(level sentry.Level, logger string) slog.Attr {
+	return slog.Any(metaKey, meta{level: level, logger: logger})
+}
+
+// tagValue is the value of the attribute returned by Tag.
+type tagValue string
+
+// userIDValue is the value of the attribute returned by UserID.
+type userIDValue string
+
+// userIDKey is the key of the attribute returned by UserID.
+const userIDKey = "user_id"
+
+// Tag returns an attribute setting the Sentry tag key to value, whatever the
+// tag prefixes of the handler.
+func Tag(key, value string) slog.Attr {
+	return slog.Any(key, tagValue(value))
+}
+
+// Err returns the attribute of the error of a record.
+func Err(err error) slog.Attr {
+	return slog.Any(longErrKey, err)
+}
+
+// Fingerprint returns an attribute replacing the fingerprint Sentry uses to
+// group the event of a record.
+func Fingerprint(parts ...string) slog.Attr {
+	return slog.Any(fingerprintKey, parts)
+}
+
+// UserID returns an attribute setting the ID of the user of the event of a
+// record, whatever the user prefix of the handler.
+func UserID(id string) slog.Attr {
+	return slog.Any(userIDKey, userIDValue(id))
+}
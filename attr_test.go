@@ -0,0 +1,76 @@
+package slogsentry
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+func TestMeta(t *testing.T) {
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	record.AddAttrs(Meta(sentry.LevelFatal, "billing"))
+
+	event := CaptureToEvent(context.Background(), record)
+	if event == nil {
+		t.Fatal("expect an event")
+	}
+	if event.Level != sentry.LevelFatal {
+		t.Errorf("expect level %q, got: %q", sentry.LevelFatal, event.Level)
+	}
+	if event.Logger != "billing" {
+		t.Errorf("expect logger %q, got: %q", "billing", event.Logger)
+	}
+	if _, ok := event.Contexts["slog"][metaKey]; ok {
+		t.Error("expect meta not in context")
+	}
+
+	record = slog.NewRecord(time.Now(), slog.LevelWarn, "the message", 0)
+	record.AddAttrs(Meta("", "billing"))
+	if event := CaptureToEvent(context.Background(), record); event.Level != sentry.LevelInfo {
+		t.Errorf("expect level from the record %q, got: %q", sentry.LevelInfo, event.Level)
+	}
+}
+
+func TestAttrHelpers(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	// No tag and user prefixes: the helpers do not depend on them.
+	handler := newTestHandler([]slog.Level{slog.LevelError})
+
+	theErr := errors.New("the error")
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	record.AddAttrs(
+		Tag("tenant", "acme"),
+		Err(theErr),
+		Fingerprint("db", "timeout"),
+		UserID("42"),
+	)
+	if err := handler.Handle(ctx, record); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("expect 1 event, got: %d", len(events))
+	}
+	event := events[0]
+	if tag := event.Tags["tenant"]; tag != "acme" {
+		t.Errorf("expect tag tenant %q, got: %q", "acme", tag)
+	}
+	if value := event.Exception[len(event.Exception)-1].Value; value != "the message: the error" {
+		t.Errorf("expect exception %q, got: %q", "the message: the error", value)
+	}
+	if !slices.Equal(event.Fingerprint, []string{"db", "timeout"}) {
+		t.Errorf("expect fingerprint [db timeout], got: %q", event.Fingerprint)
+	}
+	if event.User.ID != "42" {
+		t.Errorf("expect user ID %q, got: %q", "42", event.User.ID)
+	}
+	if len(event.Contexts["slog"]) > 0 {
+		t.Errorf("expect no slog context, got: %v", event.Contexts["slog"])
+	}
+}
@@ -0,0 +1,156 @@
+package slogsentry
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// NewBatchingSentryHandler creates a SentryHandler like NewSentryHandler,
+// which buffers the captured events and sends them in order, in batches of
+// batchSize events or every interval, whichever comes first. An interval of
+// 0 or less only sends full batches. Close sends the remaining events.
+func NewBatchingSentryHandler(
+	handler slog.Handler,
+	levels []slog.Level,
+	batchSize int,
+	interval time.Duration,
+	opts ...Option,
+) *SentryHandler {
+	s := NewSentryHandler(handler, levels, opts...)
+	s.batcher = newBatcher(s, batchSize, interval)
+	return s
+}
+
+// pendingEvent is a captured event waiting to be sent.
+type pendingEvent struct {
+	ctx    context.Context
+	hub    *sentry.Hub
+	event  *sentry.Event
+	record slog.Record
+	mode   CaptureMode
+}
+
+// batcher buffers the events of a SentryHandler and the handlers derived
+// from it.
+type batcher struct {
+	handler *SentryHandler
+	size    int
+
+	mu      sync.Mutex
+	pending []pendingEvent
+	closed  bool
+	// sending is set while a goroutine sends the pending events, without
+	// holding mu, so that records logged while sending, e.g. by the on
+	// capture hook, are only buffered, and the sender sends them in order.
+	sending bool
+	// sent is signaled when the sender is done.
+	sent *sync.Cond
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newBatcher(handler *SentryHandler, size int, interval time.Duration) *batcher {
+	b := &batcher{
+		handler: handler,
+		size:    size,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	b.sent = sync.NewCond(&b.mu)
+	if interval > 0 {
+		go b.run(interval)
+	} else {
+		close(b.done)
+	}
+	return b
+}
+
+// run sends the pending events every interval until stopped.
+func (b *batcher) run(interval time.Duration) {
+	defer close(b.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			b.flush()
+		case <-b.stop:
+			return
+		}
+	}
+}
+
+// add buffers event, and sends the batch when it is full. After close,
+// events are sent right away.
+func (b *batcher) add(ctx context.Context, hub *sentry.Hub, event *sentry.Event, record slog.Record, mode CaptureMode) {
+	b.mu.Lock()
+	b.pending = append(b.pending, pendingEvent{ctx: ctx, hub: hub, event: event, record: record, mode: mode})
+	full := b.closed || len(b.pending) >= b.size
+	b.mu.Unlock()
+
+	if full {
+		b.flush()
+	}
+}
+
+// flush sends the pending events, unless another call is sending them
+// already, which then sends them after its own.
+func (b *batcher) flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.sending {
+		return
+	}
+
+	b.sending = true
+	for len(b.pending) > 0 {
+		batch := b.pending
+		b.pending = nil
+		b.mu.Unlock()
+		for _, p := range batch {
+			b.handler.send(p.ctx, p.hub, p.event, p.record, p.mode)
+		}
+		b.mu.Lock()
+	}
+	b.sending = false
+	b.sent.Broadcast()
+}
+
+// close stops the interval and sends the pending events.
+func (b *batcher) close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	b.mu.Unlock()
+
+	close(b.stop)
+	<-b.done
+	b.flush()
+
+	// Wait for a sender in another goroutine, if any, to send the rest.
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.sending {
+		b.sent.Wait()
+	}
+}
+
+// Close sends the events buffered by a handler created with
+// NewBatchingSentryHandler, and stops buffering. It is shared with the
+// handlers derived with WithAttrs and WithGroup. Close does nothing for
+// handlers created with NewSentryHandler.
+func (s *SentryHandler) Close() error {
+	if s.batcher != nil {
+		s.batcher.close()
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package slogsentry
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+func TestBatchingSentryHandlerFlushesOnSize(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	handler := NewBatchingSentryHandler(slog.NewTextHandler(io.Discard, nil), []slog.Level{slog.LevelInfo}, 3, time.Hour)
+	defer handler.Close()
+
+	derived := handler.WithAttrs([]slog.Attr{slog.Int("n", 1)})
+	for i, msg := range []string{"one", "two", "three"} {
+		if n := len(transport.Events()); n != 0 {
+			t.Fatalf("expect no events before the batch is full, got: %d", n)
+		}
+		h := slog.Handler(handler)
+		if i == 1 {
+			h = derived
+		}
+		if err := h.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelInfo, msg, 0)); err != nil {
+			t.Fatalf("error from Handle: %s", err)
+		}
+	}
+
+	events := transport.Events()
+	if len(events) != 3 {
+		t.Fatalf("expect 3 events, got: %d", len(events))
+	}
+	for i, msg := range []string{"one", "two", "three"} {
+		if events[i].Message != msg {
+			t.Errorf("event %d: expect %q, got: %q", i, msg, events[i].Message)
+		}
+	}
+}
+
+func TestBatchingSentryHandlerFlushesOnInterval(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	handler := NewBatchingSentryHandler(slog.NewTextHandler(io.Discard, nil), []slog.Level{slog.LevelInfo}, 100, 10*time.Millisecond)
+	defer handler.Close()
+
+	if err := handler.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelInfo, "the message", 0)); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(transport.Events()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expect the event to be sent on the interval")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBatchingSentryHandlerFlushesOnClose(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	var eventIDs []*sentry.EventID
+	handler := NewBatchingSentryHandler(slog.NewTextHandler(io.Discard, nil), []slog.Level{slog.LevelInfo}, 100, 0,
+		WithOnCapture(func(_ context.Context, _ slog.Record, _ CaptureMode, eventID *sentry.EventID) {
+			eventIDs = append(eventIDs, eventID)
+		}),
+	)
+
+	if err := handler.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelInfo, "before", 0)); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+	if n := len(transport.Events()); n != 0 {
+		t.Fatalf("expect no events before Close, got: %d", n)
+	}
+	if err := handler.Close(); err != nil {
+		t.Fatalf("error from Close: %s", err)
+	}
+	if n := len(transport.Events()); n != 1 {
+		t.Fatalf("expect 1 event after Close, got: %d", n)
+	}
+
+	if err := handler.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelInfo, "after", 0)); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+	if n := len(transport.Events()); n != 2 {
+		t.Errorf("expect events to be sent right away after Close, got: %d", n)
+	}
+	if len(eventIDs) != 2 || eventIDs[0] == nil || eventIDs[1] == nil {
+		t.Errorf("expect 2 reported event IDs, got: %v", eventIDs)
+	}
+	if err := handler.Close(); err != nil {
+		t.Errorf("error from second Close: %s", err)
+	}
+}
+
+func TestBatchingSentryHandlerHookLogs(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	var logger *slog.Logger
+	handler := NewBatchingSentryHandler(slog.NewTextHandler(io.Discard, nil), []slog.Level{slog.LevelInfo}, 1, 0,
+		WithOnCapture(func(ctx context.Context, record slog.Record, _ CaptureMode, _ *sentry.EventID) {
+			if record.Message == "first" {
+				logger.InfoContext(ctx, "logged by the hook")
+			}
+		}),
+	)
+	logger = slog.New(handler)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		logger.InfoContext(ctx, "first")
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expect logging from the on capture hook not to deadlock")
+	}
+
+	events := transport.Events()
+	if len(events) != 2 || events[0].Message != "first" || events[1].Message != "logged by the hook" {
+		t.Errorf("expect both events in order, got: %v", events)
+	}
+}
@@ -0,0 +1,109 @@
+package slogsentry
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// discardTransport is a sentry.Transport that discards events, so that
+// benchmarks do not measure the growth of a recorded events slice.
+type discardTransport struct{}
+
+func (discardTransport) Configure(sentry.ClientOptions) {}
+func (discardTransport) SendEvent(*sentry.Event)        {}
+func (discardTransport) Flush(time.Duration) bool       { return true }
+
+// newBenchContext returns a context carrying a hub that sends to a
+// discardTransport.
+func newBenchContext(tb testing.TB) context.Context {
+	tb.Helper()
+	client, err := sentry.NewClient(sentry.ClientOptions{Transport: discardTransport{}})
+	if err != nil {
+		tb.Fatalf("error from sentry.NewClient: %s", err)
+	}
+	return sentry.SetHubOnContext(context.Background(), sentry.NewHub(client, sentry.NewScope()))
+}
+
+// benchRecord returns a record at level with n attributes, of which the
+// first tags are tag attributes.
+func benchRecord(level slog.Level, n, tags int) slog.Record {
+	record := slog.NewRecord(time.Now(), level, "the message", 0)
+	record.AddAttrs(slog.Any("err", errors.New("the error")))
+	for i := 0; i < n; i++ {
+		key := string(rune('a'+i%26)) + string(rune('a'+i/26))
+		if i < tags {
+			key = "t_" + key
+		}
+		record.AddAttrs(slog.Int(key, i))
+	}
+	return record
+}
+
+var benchHandleCases = []struct {
+	name  string
+	level slog.Level
+	attrs int
+	tags  int
+}{
+	{name: "NotCaptured", level: slog.LevelInfo},
+	{name: "NotCapturedAttrs", level: slog.LevelInfo, attrs: 10},
+	{name: "Captured", level: slog.LevelError},
+	{name: "CapturedAttrs", level: slog.LevelError, attrs: 10},
+	{name: "CapturedAttrsTags", level: slog.LevelError, attrs: 10, tags: 5},
+	{name: "CapturedWide", level: slog.LevelError, attrs: 20},
+}
+
+func BenchmarkHandle(b *testing.B) {
+	for _, bc := range benchHandleCases {
+		b.Run(bc.name, func(b *testing.B) {
+			ctx := newBenchContext(b)
+			handler := newTestHandler([]slog.Level{slog.LevelError}, WithTagPrefix("t_"))
+			record := benchRecord(bc.level, bc.attrs, bc.tags)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := handler.Handle(ctx, record); err != nil {
+					b.Fatalf("error from Handle: %s", err)
+				}
+			}
+		})
+	}
+}
+
+// TestHandleAllocs guards the allocations of Handle. Records that are not
+// captured only cost the allocations of the wrapped handler: one, for the
+// TextHandler formatting the error. The budgets of captured records include
+// the allocations of sentry-go, with some headroom for changes.
+func TestHandleAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector changes the allocations")
+	}
+	budgets := map[string]float64{
+		"NotCaptured":       1,
+		"NotCapturedAttrs":  1,
+		"Captured":          90,
+		"CapturedAttrs":     115,
+		"CapturedAttrsTags": 110,
+		"CapturedWide":      145,
+	}
+	for _, bc := range benchHandleCases {
+		t.Run(bc.name, func(t *testing.T) {
+			ctx := newBenchContext(t)
+			handler := newTestHandler([]slog.Level{slog.LevelError}, WithTagPrefix("t_"))
+			record := benchRecord(bc.level, bc.attrs, bc.tags)
+
+			allocs := testing.AllocsPerRun(100, func() {
+				_ = handler.Handle(ctx, record)
+			})
+			if budget := budgets[bc.name]; allocs > budget {
+				t.Errorf("expect at most %v allocations, got: %v", budget, allocs)
+			}
+		})
+	}
+}
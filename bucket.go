@@ -0,0 +1,56 @@
+package slogsentry
+
+import (
+	"log/slog"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// tagBucket returns the bucket label of the numeric value of attr, when its
+// key is bucketed with WithTagBucket; durations are in milliseconds. It
+// reports false for other attributes.
+func (s *SentryHandler) tagBucket(attr slog.Attr) (string, bool) {
+	boundaries, ok := s.tagBuckets[attr.Key]
+	if !ok {
+		return "", false
+	}
+
+	var value float64
+	switch attr.Value.Kind() {
+	case slog.KindInt64:
+		value = float64(attr.Value.Int64())
+	case slog.KindUint64:
+		value = float64(attr.Value.Uint64())
+	case slog.KindFloat64:
+		value = attr.Value.Float64()
+	case slog.KindDuration:
+		value = float64(attr.Value.Duration()) / float64(time.Millisecond)
+	default:
+		return "", false
+	}
+	return bucketLabel(value, boundaries), true
+}
+
+// bucketLabel returns the label of the bucket of value between the sorted
+// boundaries: "<b0" below the first, "b0-b1" from one up to the next, and
+// ">=bn" from the last.
+func bucketLabel(value float64, boundaries []float64) string {
+	i, found := slices.BinarySearch(boundaries, value)
+	if found {
+		// The bucket starting at a boundary includes it.
+		i++
+	}
+	switch i {
+	case 0:
+		return "<" + formatBoundary(boundaries[0])
+	case len(boundaries):
+		return ">=" + formatBoundary(boundaries[i-1])
+	default:
+		return formatBoundary(boundaries[i-1]) + "-" + formatBoundary(boundaries[i])
+	}
+}
+
+func formatBoundary(boundary float64) string {
+	return strconv.FormatFloat(boundary, 'f', -1, 64)
+}
@@ -0,0 +1,22 @@
+package slogsentry
+
+import "context"
+
+// captureKey is the context key of the flag set with ContextWithCapture.
+type captureKey struct{}
+
+// ContextWithCapture returns a copy of ctx in which records are captured
+// when capture is true, whatever their level, or never captured when it is
+// false, e.g. for requests a middleware considers privacy sensitive. Records
+// at the panic level still panic. Only records the wrapped handler is enabled
+// for reach the SentryHandler.
+func ContextWithCapture(ctx context.Context, capture bool) context.Context {
+	return context.WithValue(ctx, captureKey{}, capture)
+}
+
+// captureFromContext returns the flag set with ContextWithCapture, and
+// whether it is set.
+func captureFromContext(ctx context.Context) (capture, ok bool) {
+	capture, ok = ctx.Value(captureKey{}).(bool)
+	return capture, ok
+}
@@ -0,0 +1,61 @@
+package slogsentry
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+func TestContextWithCapture(t *testing.T) {
+	handler := NewSentryHandler(
+		slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		[]slog.Level{slog.LevelError},
+	)
+	tests := []struct {
+		capture       *bool
+		level         slog.Level
+		expectCapture bool
+	}{
+		{nil, slog.LevelDebug, false},
+		{nil, slog.LevelError, true},
+		{ptr(true), slog.LevelDebug, true},
+		{ptr(false), slog.LevelError, false},
+	}
+
+	for i, test := range tests {
+		ctx, transport := newTestContext(t, sentry.ClientOptions{})
+		if test.capture != nil {
+			ctx = ContextWithCapture(ctx, *test.capture)
+		}
+		if err := handler.Handle(ctx, slog.NewRecord(time.Now(), test.level, "the message", 0)); err != nil {
+			t.Fatalf("test %d: error from Handle: %s", i, err)
+		}
+		if captured := len(transport.Events()) == 1; captured != test.expectCapture {
+			t.Errorf("test %d: expect captured %t, got: %t", i, test.expectCapture, captured)
+		}
+	}
+}
+
+func TestContextWithCaptureDisabledStillPanics(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	var panicked bool
+	handler := newTestHandler(nil, WithPanicLevel(slog.LevelError), WithPanicHandler(func(context.Context, error) { panicked = true }))
+
+	if err := handler.Handle(ContextWithCapture(ctx, false), slog.NewRecord(time.Now(), slog.LevelError, "fatal", 0)); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+	if !panicked {
+		t.Error("expect the panic handler to be called")
+	}
+	if n := len(transport.Events()); n != 0 {
+		t.Errorf("expect no events, got: %d", n)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
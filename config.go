@@ -0,0 +1,93 @@
+package slogsentry
+
+import "log/slog"
+
+// Config lists the attribute keys and key prefixes a SentryHandler treats
+// specially, so that callers can avoid collisions with their own keys.
+type Config struct {
+	// Levels are the levels captured to Sentry.
+	Levels []slog.Level
+	// AllLevels reports whether every level is captured, see WithAllLevels.
+	AllLevels bool
+	// ErrorKeys are the keys of the attribute holding the error of a record,
+	// or a []error of its errors.
+	ErrorKeys []string
+	// FingerprintKey is the key of the attribute replacing the fingerprint.
+	FingerprintKey string
+	// MetaKey is the key of the attribute returned by Meta.
+	MetaKey string
+	// SkipKey is the key of the attribute that, when true, skips capturing
+	// the record.
+	SkipKey string
+	// DistKey is the key of the attribute setting the distribution.
+	DistKey string
+	// HTTPRequestKey is the key of the attribute setting the request.
+	HTTPRequestKey string
+	// MonitorSlugKey is the key of the attribute making the record a cron
+	// check-in of the monitor.
+	MonitorSlugKey string
+	// MonitorStatusKey is the key of the attribute setting the status of a
+	// check-in.
+	MonitorStatusKey string
+	// ValidationErrorsKey is the key of the attribute with the validation
+	// errors of a record.
+	ValidationErrorsKey string
+	// MeasurementPrefix is the key prefix of the attributes with the
+	// measurements of a record.
+	MeasurementPrefix string
+	// UserIDKey is the key of the attribute returned by UserID.
+	UserIDKey string
+	// TagPrefixes are the key prefixes of the attributes set as tags.
+	TagPrefixes []string
+	// TagGroup is the name of the group whose attributes are set as tags. It
+	// is empty when no group sets tags.
+	TagGroup string
+	// UserPrefix is the key prefix of the attributes setting the user. It is
+	// empty when no attributes set the user.
+	UserPrefix string
+	// TagsOverflowKey is the context key of the tags exceeding the maximum.
+	TagsOverflowKey string
+	// DroppedAttrsKey is the context key of the number of attributes
+	// exceeding the maximum.
+	DroppedAttrsKey string
+	// IgnoredKeys are the keys of slog's built-in attributes, which are not
+	// added to the context.
+	IgnoredKeys []string
+}
+
+// Config returns the Config currently in effect for the handler.
+func (s *SentryHandler) Config() Config {
+	return Config{
+		Levels:              append([]slog.Level(nil), s.levels...),
+		AllLevels:           s.allLevels,
+		ErrorKeys:           []string{shortErrKey, longErrKey, errorsKey},
+		FingerprintKey:      fingerprintKey,
+		MetaKey:             metaKey,
+		SkipKey:             skipKey,
+		DistKey:             distKey,
+		HTTPRequestKey:      httpRequestKey,
+		MonitorSlugKey:      monitorSlugKey,
+		MonitorStatusKey:    monitorStatusKey,
+		ValidationErrorsKey: validationErrorsKey,
+		MeasurementPrefix:   measurementPrefix,
+		UserIDKey:           userIDKey,
+		TagPrefixes:         append([]string(nil), s.tagPrefixes...),
+		TagGroup:            s.tagGroup,
+		UserPrefix:          s.userPrefix,
+		TagsOverflowKey:     tagsOverflowKey,
+		DroppedAttrsKey:     droppedAttrsKey,
+		IgnoredKeys:         s.ignoredKeys(),
+	}
+}
+
+// ignoredKeys returns the keys of slog's built-in attributes not added to the
+// context, see WithKeepDefaultKeys.
+func (s *SentryHandler) ignoredKeys() []string {
+	var keys []string
+	for _, key := range []string{slog.TimeKey, slog.LevelKey, slog.SourceKey, slog.MessageKey} {
+		if s.ignoresKey(key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,45 @@
+package slogsentry
+
+import (
+	"log/slog"
+	"reflect"
+	"testing"
+)
+
+func TestSentryHandlerConfig(t *testing.T) {
+	levels := []slog.Level{slog.LevelWarn, slog.LevelError}
+	handler := newTestHandler(levels, WithTagPrefix("tag_"), WithUserPrefix("user_"))
+
+	expect := Config{
+		Levels:              levels,
+		ErrorKeys:           []string{"err", "error", "errors"},
+		FingerprintKey:      "fingerprint",
+		MetaKey:             "sentry_meta",
+		SkipKey:             "sentry_skip",
+		DistKey:             "dist",
+		HTTPRequestKey:      "http_request",
+		MonitorSlugKey:      "monitor_slug",
+		MonitorStatusKey:    "monitor_status",
+		ValidationErrorsKey: "validation_errors",
+		MeasurementPrefix:   "measurement_",
+		UserIDKey:           "user_id",
+		TagPrefixes:         []string{"tag_"},
+		UserPrefix:          "user_",
+		TagsOverflowKey:     "_tags_overflow",
+		DroppedAttrsKey:     "_attrs_dropped",
+		IgnoredKeys:         []string{"time", "level", "source", "msg"},
+	}
+	if config := handler.Config(); !reflect.DeepEqual(config, expect) {
+		t.Errorf("expect: %+v, got: %+v", expect, config)
+	}
+
+	if config := newTestHandler(levels).Config(); len(config.TagPrefixes) != 0 {
+		t.Errorf("expect no tag prefixes by default, got: %q", config.TagPrefixes)
+	}
+}
+
+func TestSentryHandlerConfigAllLevels(t *testing.T) {
+	if config := newTestHandler(nil, WithAllLevels()).Config(); !config.AllLevels {
+		t.Error("expect AllLevels with WithAllLevels")
+	}
+}
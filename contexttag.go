@@ -0,0 +1,40 @@
+package slogsentry
+
+import (
+	"context"
+	"sync"
+)
+
+// contextTags maps context keys to the names of the tags set from their
+// values. It is shared by the handlers derived with WithAttrs and WithGroup.
+type contextTags struct {
+	mu   sync.RWMutex
+	tags map[any]string
+}
+
+func newContextTags() *contextTags {
+	return &contextTags{tags: map[any]string{}}
+}
+
+// RegisterContextTag sets the tag tagName of events from the value stored
+// under key in the context of the record, if any. Like for context.WithValue,
+// key should be of an unexported type of the caller, so that it is type-safe.
+// Registering a key again replaces its tag name. The registration applies to
+// the handlers derived from s with WithAttrs and WithGroup too, and is safe
+// for concurrent use with Handle.
+func (s *SentryHandler) RegisterContextTag(key any, tagName string) {
+	s.contextTags.mu.Lock()
+	defer s.contextTags.mu.Unlock()
+	s.contextTags.tags[key] = tagName
+}
+
+// collect calls fn for every registered key with a value in ctx.
+func (c *contextTags) collect(ctx context.Context, fn func(tagName string, value any)) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for key, tagName := range c.tags {
+		if value := ctx.Value(key); value != nil {
+			fn(tagName, value)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package slogsentry
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// regionKey and requestIDKey are typed context keys.
+type (
+	regionKey    struct{}
+	requestIDKey int
+)
+
+func TestRegisterContextTag(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	handler := newTestHandler([]slog.Level{slog.LevelError}, WithTagPrefix("tag_"))
+	derived := handler.WithGroup("http")
+	handler.RegisterContextTag(regionKey{}, "region")
+	handler.RegisterContextTag(requestIDKey(0), "request_id")
+
+	ctx = context.WithValue(ctx, regionKey{}, "eu")
+	ctx = context.WithValue(ctx, requestIDKey(0), 42)
+	// A different key of the same type is not registered.
+	ctx = context.WithValue(ctx, requestIDKey(1), 43)
+
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	record.AddAttrs(slog.String("tag_region", "us"))
+	for _, h := range []slog.Handler{handler, derived} {
+		if err := h.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)); err != nil {
+			t.Fatalf("error from Handle: %s", err)
+		}
+	}
+	if err := handler.Handle(ctx, record); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+
+	events := transport.Events()
+	if len(events) != 3 {
+		t.Fatalf("expect 3 events, got: %d", len(events))
+	}
+	for i, expected := range []map[string]string{
+		{"region": "eu", "request_id": "42"},
+		{"region": "eu", "request_id": "42"},
+		// Tag attributes override the context tags.
+		{"region": "us", "request_id": "42"},
+	} {
+		if tags := events[i].Tags; !reflect.DeepEqual(tags, expected) {
+			t.Errorf("event %d: expect tags %v, got: %v", i, expected, tags)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package slogsentry
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// errorDeduper drops records with the same root error within a window of
+// time. It is shared by the handlers derived with WithAttrs and WithGroup.
+type errorDeduper struct {
+	window time.Duration
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// allow reports whether a record with err at now is not a duplicate, and
+// next, e.g. the rate limiter, allows it too. Only allowed errors are seen,
+// so that an error dropped by next is not a duplicate later. The deepest
+// error wrapped by err identifies it, by type and message.
+func (d *errorDeduper) allow(err error, now time.Time, next func() bool) bool {
+	root := rootError(err)
+	key := fmt.Sprintf("%T:%s", root, root.Error())
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if seen, ok := d.seen[key]; ok && now.Sub(seen) < d.window {
+		return false
+	}
+	if !next() {
+		return false
+	}
+	for k, seen := range d.seen {
+		if now.Sub(seen) >= d.window {
+			delete(d.seen, k)
+		}
+	}
+	d.seen[key] = now
+	return true
+}
+
+// reset forgets the errors seen.
+func (d *errorDeduper) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	clear(d.seen)
+}
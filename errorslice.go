@@ -0,0 +1,53 @@
+package slogsentry
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// errorsKey is the key of an attribute with the errors of a record, besides
+// the error keys.
+const errorsKey = "errors"
+
+// errorSliceFromAttr returns the errors of an attribute with an error key,
+// or the errors key, and a []error value joined into one error. It reports
+// false for other attributes.
+func errorSliceFromAttr(attr slog.Attr) (error, bool) {
+	if attr.Key != shortErrKey && attr.Key != longErrKey && attr.Key != errorsKey {
+		return nil, false
+	}
+	if attr.Value.Kind() != slog.KindAny {
+		return nil, false
+	}
+	errs, ok := attr.Value.Any().([]error)
+	if !ok {
+		return nil, false
+	}
+	return errors.Join(errs...), true
+}
+
+// joinedExceptions returns the exceptions of the errors joined in the chain
+// of err, e.g. by errors.Join, as Sentry does not follow them itself. The
+// exceptions of each error are ordered like Event.SetException orders them,
+// and only have the stack traces of the errors themselves.
+func joinedExceptions(err error, maxErrorDepth int) []sentry.Exception {
+	for ; err != nil; err = errors.Unwrap(err) {
+		joined, ok := err.(interface{ Unwrap() []error })
+		if !ok {
+			continue
+		}
+		var exceptions []sentry.Exception
+		for _, err := range joined.Unwrap() {
+			var event sentry.Event
+			event.SetException(err, maxErrorDepth)
+			if len(event.Exception) > 0 {
+				event.Exception[len(event.Exception)-1].Stacktrace = sentry.ExtractStacktrace(err)
+			}
+			exceptions = append(exceptions, event.Exception...)
+		}
+		return exceptions
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package slogsentry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestErrorSliceAttr(t *testing.T) {
+	errs := []error{errors.New("disk full"), fmt.Errorf("send mail: %w", errors.New("timeout"))}
+
+	for _, key := range []string{"err", "errors"} {
+		record := slog.NewRecord(time.Now(), slog.LevelError, "cleanup failed", 0)
+		record.AddAttrs(slog.Any(key, errs))
+
+		event := CaptureToEvent(context.Background(), record)
+		if event == nil {
+			t.Fatal("expect an event")
+		}
+		var values []string
+		for _, exception := range event.Exception {
+			values = append(values, exception.Value)
+		}
+		expected := []string{
+			"disk full",
+			"timeout",
+			"send mail: timeout",
+			"disk full\nsend mail: timeout",
+			"cleanup failed: disk full\nsend mail: timeout",
+		}
+		if !slices.Equal(values, expected) {
+			t.Errorf("%s: expect exceptions %q, got: %q", key, expected, values)
+		}
+		if mechanism := event.Exception[len(event.Exception)-1].Mechanism; mechanism == nil || mechanism.Type != "slog" {
+			t.Errorf("%s: expect the slog mechanism on the last exception, got: %v", key, mechanism)
+		}
+		if _, ok := event.Contexts["slog"][key]; ok {
+			t.Errorf("%s: expect no %s in the slog context", key, key)
+		}
+	}
+}
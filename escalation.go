@@ -0,0 +1,50 @@
+package slogsentry
+
+import (
+	"sync"
+	"time"
+)
+
+// warnEscalator counts warnings by message in a fixed window of time from
+// their first occurrence, to escalate persistent ones. It is shared by the
+// handlers derived with WithAttrs and WithGroup.
+type warnEscalator struct {
+	threshold int
+	window    time.Duration
+
+	mu     sync.Mutex
+	counts map[string]*warnCount
+}
+
+// warnCount counts the occurrences of a warning since start.
+type warnCount struct {
+	start time.Time
+	count int
+}
+
+// escalate reports whether a warning with msg at now reached the threshold
+// of occurrences in its window.
+func (e *warnEscalator) escalate(msg string, now time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for k, c := range e.counts {
+		if now.Sub(c.start) >= e.window {
+			delete(e.counts, k)
+		}
+	}
+	c, ok := e.counts[msg]
+	if !ok {
+		c = &warnCount{start: now}
+		e.counts[msg] = c
+	}
+	c.count++
+	return c.count >= e.threshold
+}
+
+// reset forgets the warnings counted.
+func (e *warnEscalator) reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	clear(e.counts)
+}
@@ -0,0 +1,66 @@
+package slogsentry
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// EventIDHeader is the response header SetEventIDHeader sets.
+const EventIDHeader = "Sentry-Event-Id"
+
+// eventIDKey is the context key of the eventIDCarrier.
+type eventIDKey struct{}
+
+// eventIDCarrier holds the ID of the last event captured for a context.
+type eventIDCarrier struct {
+	mu      sync.Mutex
+	eventID *sentry.EventID
+}
+
+func (c *eventIDCarrier) set(eventID *sentry.EventID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.eventID = eventID
+}
+
+func (c *eventIDCarrier) get() *sentry.EventID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.eventID
+}
+
+// ContextWithEventID returns a copy of ctx in which a SentryHandler stores
+// the ID of the events it captures for records logged with the context, so
+// that EventIDFromContext can retrieve it, e.g. to show a reference to the
+// user.
+func ContextWithEventID(ctx context.Context) context.Context {
+	return context.WithValue(ctx, eventIDKey{}, &eventIDCarrier{})
+}
+
+// EventIDFromContext returns the ID of the last event captured for a record
+// logged with ctx, or a context derived from it. It returns nil when no event
+// was captured, or ctx does not derive from ContextWithEventID.
+func EventIDFromContext(ctx context.Context) *sentry.EventID {
+	if carrier, ok := ctx.Value(eventIDKey{}).(*eventIDCarrier); ok {
+		return carrier.get()
+	}
+	return nil
+}
+
+// SetEventIDHeader sets the Sentry-Event-Id header of w to id, so that
+// clients can refer to the event, e.g. with
+//
+//	SetEventIDHeader(w, EventIDFromContext(r.Context()))
+//
+// after logging with a context derived from ContextWithEventID. It sets no
+// header when id is nil or empty. Like other headers, it must be set before
+// the response is written.
+func SetEventIDHeader(w http.ResponseWriter, id *sentry.EventID) {
+	if id == nil || *id == "" {
+		return
+	}
+	w.Header().Set(EventIDHeader, string(*id))
+}
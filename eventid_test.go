@@ -0,0 +1,61 @@
+package slogsentry
+
+import (
+	"context"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+)
+
+func TestEventIDFromContext(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	logger := slog.New(newTestHandler([]slog.Level{slog.LevelError}))
+
+	if eventID := EventIDFromContext(ctx); eventID != nil {
+		t.Errorf("expect no event ID without ContextWithEventID, got: %s", *eventID)
+	}
+
+	ctx = ContextWithEventID(ctx)
+	if eventID := EventIDFromContext(ctx); eventID != nil {
+		t.Errorf("expect no event ID before capturing, got: %s", *eventID)
+	}
+
+	logger.InfoContext(ctx, "not captured")
+	if eventID := EventIDFromContext(ctx); eventID != nil {
+		t.Errorf("expect no event ID for a record not captured, got: %s", *eventID)
+	}
+
+	logger.ErrorContext(context.WithValue(ctx, tenantKey{}, "acme"), "the message")
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("expect 1 event, got: %d", len(events))
+	}
+	eventID := EventIDFromContext(ctx)
+	if eventID == nil || *eventID != events[0].EventID {
+		t.Errorf("expect event ID %q, got: %v", events[0].EventID, eventID)
+	}
+}
+
+func TestSetEventIDHeader(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	logger := slog.New(newTestHandler([]slog.Level{slog.LevelError}))
+
+	ctx = ContextWithEventID(ctx)
+	w := httptest.NewRecorder()
+	SetEventIDHeader(w, EventIDFromContext(ctx))
+	if header := w.Header().Get("Sentry-Event-Id"); header != "" {
+		t.Errorf("expect no header without an event, got: %q", header)
+	}
+
+	logger.ErrorContext(ctx, "the message")
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("expect 1 event, got: %d", len(events))
+	}
+	SetEventIDHeader(w, EventIDFromContext(ctx))
+	if header := w.Header().Get("Sentry-Event-Id"); header != string(events[0].EventID) {
+		t.Errorf("expect header %q, got: %q", events[0].EventID, header)
+	}
+}
@@ -2,16 +2,49 @@ package slogsentry
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"maps"
+	"reflect"
 	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/getsentry/sentry-go"
 )
 
 const (
-	shortErrKey = "err"
-	longErrKey  = "error"
+	shortErrKey    = "err"
+	longErrKey     = "error"
+	fingerprintKey = "fingerprint"
+	skipKey        = "sentry_skip"
+	distKey        = "dist"
+
+	tagsOverflowKey = "_tags_overflow"
+	droppedAttrsKey = "_attrs_dropped"
+	loggerNameTag   = "logger_name"
+	attrErrorTag    = "_slog_attr_error"
+	levelTag        = "log_level"
+
+	durationMsSuffix = "_ms"
+
+	// defaultFlushTimeout is the default timeout of flushing the hub.
+	defaultFlushTimeout = 2 * time.Second
+
+	// defaultMaxErrorDepth is Sentry's default for ClientOptions.MaxErrorDepth.
+	defaultMaxErrorDepth = 10
+
+	// defaultMechanismType is the default mechanism type of exceptions.
+	defaultMechanismType = "slog"
+
+	// defaultMaxTags is the default maximum number of tags set on an event.
+	defaultMaxTags = 50
+
+	// defaultFingerprintSep separates the parts of a fingerprint string.
+	defaultFingerprintSep = ","
 )
 
 var slogDefaultKeys = []string{slog.TimeKey, slog.LevelKey, slog.SourceKey, slog.MessageKey, shortErrKey, longErrKey}
@@ -38,22 +71,144 @@ func (e SlogError) Unwrap() error {
 	return e.err
 }
 
+// Format implements fmt.Formatter. The %+v verb formats the wrapped error
+// with %+v too, so errors with a verbose form, e.g. including a stack trace,
+// keep it.
+func (e SlogError) Format(f fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && f.Flag('+'):
+		_, _ = io.WriteString(f, e.msg)
+		if e.err != nil {
+			if len(e.msg) > 0 {
+				_, _ = io.WriteString(f, ": ")
+			}
+			fmt.Fprintf(f, "%+v", e.err)
+		}
+	case verb == 'q':
+		fmt.Fprintf(f, "%q", e.Error())
+	default:
+		_, _ = io.WriteString(f, e.Error())
+	}
+}
+
+// capturedKey is the context key marking a record as captured, so that a
+// SentryHandler wrapping another SentryHandler captures it only once.
+type capturedKey struct{}
+
 // SentryHandler is a Handler that writes log records to the Sentry.
 type SentryHandler struct {
 	slog.Handler
 	levels []slog.Level
+
+	panicLevel             *slog.Level
+	panicHandler           func(ctx context.Context, err error)
+	attachStacktrace       bool
+	warnAsException        bool
+	tagPrefixes            []string
+	maxTags                int
+	beforeCapture          func(ctx context.Context, event *sentry.Event, record slog.Record) *sentry.Event
+	onCapture              func(ctx context.Context, record slog.Record, mode CaptureMode, eventID *sentry.EventID)
+	captureStrategy        func(ctx context.Context, record slog.Record, mode CaptureMode) CaptureMode
+	contextProvider        func(ctx context.Context) map[string]any
+	messageOnlyWhenNoError bool
+	mechanismType          string
+	mechanismHandled       bool
+	levelHubs              map[slog.Level]*sentry.Hub
+	rateLimiter            *rateLimiter
+	now                    func() time.Time
+	groupPathTag           string
+	maxAttrs               int
+	useExtra               bool
+	sourceLocation         bool
+	batcher                *batcher
+	replaceAttr            func(groups []string, attr slog.Attr) slog.Attr
+	name                   string
+	maxValueLength         int
+	tagFunc                func(ctx context.Context, record slog.Record) map[string]string
+	reportInnerErrors      bool
+	errorTypeTag           string
+	environment            string
+	errorDeduper           *errorDeduper
+	allLevels              bool
+	userPrefix             string
+	userFromContext        func(ctx context.Context) *sentry.User
+	levelTag               bool
+	errorExtras            bool
+	eventModifier          func(ctx context.Context, event *sentry.Event)
+	eventProcessors        []sentry.EventProcessor
+	warnEscalator          *warnEscalator
+	flushTimeout           time.Duration
+	fingerprintSep         string
+	sendAsLogs             []slog.Level
+	ignoreErrors           []error
+	ignoreErrorTypes       []reflect.Type
+	dist                   string
+	groupTagNamespace      bool
+	errorChainBreadcrumbs  bool
+	groupAsContextSection  bool
+	groupInMessage         bool
+	nilHubWarning          *onceWarning
+	jsonBytes              bool
+	extraKeys              []string
+	keepDefaultKeys        []string
+	messageAsFingerprint   bool
+	messageFormatter       func(ctx context.Context, record slog.Record, err error) string
+	tagGroup               string
+	contextTags            *contextTags
+	stacktraceMinLevel     *slog.Level
+	disableContext         bool
+	filter                 func(ctx context.Context, record slog.Record) bool
+	levelNames             map[string]sentry.Level
+	stringErrors           bool
+	alwaysException        bool
+	maxContextSize         int
+	maxBreadcrumbDataSize  int
+	routing                func(ctx context.Context, record slog.Record, tags map[string]string) *sentry.Hub
+	tagBuckets             map[string][]float64
+	maxDepth               int
+	flushLevels            []slog.Level
+	modifyScope            func(scope *sentry.Scope, ctx context.Context, record slog.Record)
+
+	// storedAttrs are the prepared attributes added with WithAttrs.
+	storedAttrs []storedAttr
+	// storedMonitor reports whether storedAttrs has a monitor slug attribute.
+	storedMonitor bool
+	// groups are the names of the groups added with WithGroup.
+	groups []string
 }
 
 // NewSentryHandler creates a SentryHandler that writes to w,
-// using the given options.
+// using the given options. Records at the given levels are captured; no
+// levels capture nothing, unless WithAllLevels is passed. The options set
+// with SetDefaultOptions are applied first, so the given options override
+// them.
 func NewSentryHandler(
 	handler slog.Handler,
 	levels []slog.Level,
+	opts ...Option,
 ) *SentryHandler {
-	return &SentryHandler{
+	s := &SentryHandler{
 		Handler: handler,
-		levels:  levels,
+		levels:  slices.Clone(levels),
+		maxTags: defaultMaxTags,
+
+		mechanismType:    defaultMechanismType,
+		mechanismHandled: true,
+		now:              time.Now,
+		maxValueLength:   defaultMaxValueLength,
+		maxDepth:         defaultMaxDepth,
+		flushTimeout:     defaultFlushTimeout,
+		fingerprintSep:   defaultFingerprintSep,
+		nilHubWarning:    newOnceWarning(),
+		contextTags:      newContextTags(),
+	}
+	for _, opt := range defaultOptions() {
+		opt(s)
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // Enabled reports whether the handler handles records at the given level.
@@ -61,56 +216,852 @@ func (s *SentryHandler) Enabled(ctx context.Context, level slog.Level) bool {
 	return s.Handler.Enabled(ctx, level)
 }
 
+// WillCapture reports whether the handler captures records at level to
+// Sentry, so callers can avoid building expensive attributes otherwise.
+// Unlike Enabled it does not depend on the wrapped handler, nor on the
+// context, see ContextWithCapture.
+func (s *SentryHandler) WillCapture(level slog.Level) bool {
+	return s.panics(level) || s.captures(level)
+}
+
 // Handle intercepts and processes logger messages.
 // In our case, send a message to the Sentry.
+//
+// Handle does not modify record, since the attributes of copies of a record
+// share storage, e.g. when a record is passed to several handlers. Changing
+// the record before passing it on requires record.Clone.
 func (s *SentryHandler) Handle(ctx context.Context, record slog.Record) error {
-	if slices.Contains(s.levels, record.Level) {
-		hub := sentry.GetHubFromContext(ctx)
-		if hub == nil {
-			hub = sentry.CurrentHub()
-		}
-		if hub == nil {
-			return fmt.Errorf("sentry: hub is nil")
-		}
-		var err error
-		slogContext := map[string]any{}
-		record.Attrs(func(attr slog.Attr) bool {
-			if !slices.Contains(slogDefaultKeys, attr.Key) {
-				slogContext[attr.Key] = attr.Value.String()
-			} else if attr.Key == shortErrKey || attr.Key == longErrKey {
-				var ok bool
-				err, ok = attr.Value.Any().(error)
-				if !ok {
-					slogContext[attr.Key] = attr.Value.String()
+	if ctx.Value(capturedKey{}) != nil {
+		// An outer SentryHandler already captured the record.
+		return s.handle(ctx, record)
+	}
+
+	panics := s.panics(record.Level)
+	capture := panics || s.captures(record.Level) || s.monitors(record)
+	if force, ok := captureFromContext(ctx); ok {
+		capture = force
+	}
+	if capture && s.filter != nil && !s.filter(ctx, record) {
+		capture = false
+	}
+	if panics || capture {
+		attrs := s.collectAttrs(ctx, record)
+		hub := s.routedHub(ctx, record, attrs)
+		switch {
+		case !capture:
+			// Capturing is disabled for the context or filtered out, but the
+			// record panics.
+		case hub == nil:
+			s.warnNilHub(ctx)
+		case attrs.monitorSlug != "" && !panics:
+			if !attrs.skip && !s.ignores(attrs.err) {
+				s.checkIn(hub, record, attrs)
+			}
+		default:
+			s.captureRecord(ctx, hub, record, attrs, panics)
+		}
+		ctx = context.WithValue(ctx, capturedKey{}, true)
+
+		if panics {
+			s.flush(hub)
+			handleErr := s.handle(ctx, record)
+			s.panic(ctx, SlogError{msg: s.message(ctx, record, attrs.err), err: attrs.err})
+			return handleErr
+		}
+	}
+
+	return s.handle(ctx, record)
+}
+
+// levelName returns the name of level as the wrapped handler would write
+// it: the level attribute after WithReplaceAttr, e.g. "NOTICE" for a custom
+// level, else its String form.
+func (s *SentryHandler) levelName(level slog.Level) string {
+	if s.levelNames == nil {
+		return ""
+	}
+	if s.replaceAttr == nil {
+		return level.String()
+	}
+	return resolve(s.replaceAttr(nil, slog.Any(slog.LevelKey, level)).Value).String()
+}
+
+// captureRecord captures record as an event, or adds it as a breadcrumb,
+// by its capture mode. Records that panic are always captured.
+func (s *SentryHandler) captureRecord(ctx context.Context, hub *sentry.Hub, record slog.Record, attrs recordAttrs, panics bool) {
+	mode, level := s.captureMode(ctx, record, attrs, panics)
+	if mode == ModeSkip {
+		return
+	}
+	event := s.buildEvent(ctx, hub, record, attrs, mode, level)
+	switch {
+	case mode == ModeBreadcrumb:
+		s.addBreadcrumb(hub, event)
+		if s.onCapture != nil {
+			s.onCapture(ctx, record, mode, nil)
+		}
+	case panics || s.allow(attrs.err):
+		s.capture(ctx, hub, event, record, mode)
+		if !panics && slices.Contains(s.flushLevels, record.Level) {
+			s.flush(hub)
+		}
+	}
+}
+
+// flush sends the events buffered by the batcher, if any, and waits for hub,
+// unless nil, to deliver its events, up to the flush timeout.
+func (s *SentryHandler) flush(hub *sentry.Hub) {
+	if s.batcher != nil {
+		s.batcher.flush()
+	}
+	if hub != nil {
+		hub.Flush(s.flushTimeout)
+	}
+}
+
+// warnNilHub logs a warning with the wrapped handler that records are not
+// captured for lack of a hub, e.g. a nil hub passed to WithLevelHub. It
+// warns once, rather than for every record, until ResetState.
+func (s *SentryHandler) warnNilHub(ctx context.Context) {
+	if s.nilHubWarning == nil {
+		return
+	}
+	s.nilHubWarning.do(func() {
+		if s.Handler.Enabled(ctx, slog.LevelWarn) {
+			_ = s.Handler.Handle(ctx, slog.NewRecord(s.now(), slog.LevelWarn, nilHubMessage, 0))
+		}
+	})
+}
+
+// handle passes record to the wrapped handler. When enabled, an error of the
+// wrapped handler is added as a breadcrumb, so that Sentry shows failures
+// of the local logging.
+func (s *SentryHandler) handle(ctx context.Context, record slog.Record) error {
+	err := s.Handler.Handle(ctx, record)
+	if hub := s.hub(ctx, record.Level); err != nil && s.reportInnerErrors && hub != nil {
+		hub.AddBreadcrumb(&sentry.Breadcrumb{
+			Type:      "error",
+			Category:  "slog",
+			Message:   fmt.Sprintf("slog: handle %q: %s", record.Message, err),
+			Level:     sentry.LevelError,
+			Timestamp: s.now(),
+		}, nil)
+	}
+	return err
+}
+
+// CaptureToEvent returns the event that a SentryHandler created with opts
+// sends to Sentry for record, without sending it. The hub the record would
+// be captured with provides the client options. It returns nil when the
+// record is not captured, e.g. because of a skip attribute, an ignored error,
+// the filter set with WithFilter or ContextWithCapture.
+func CaptureToEvent(ctx context.Context, record slog.Record, opts ...Option) *sentry.Event {
+	s := NewSentryHandler(nil, nil, opts...)
+	if capture, ok := captureFromContext(ctx); ok && !capture {
+		return nil
+	}
+	if s.filter != nil && !s.filter(ctx, record) {
+		return nil
+	}
+	attrs := s.collectAttrs(ctx, record)
+	mode, level := s.captureMode(ctx, record, attrs, s.panics(record.Level))
+	if mode == ModeSkip {
+		return nil
+	}
+	return s.buildEvent(ctx, s.routedHub(ctx, record, attrs), record, attrs, mode, level)
+}
+
+// routedHub returns the hub capturing record: the hub WithRouting routes it
+// to by its tags, else the hub for its level.
+func (s *SentryHandler) routedHub(ctx context.Context, record slog.Record, attrs recordAttrs) *sentry.Hub {
+	if s.routing != nil {
+		if hub := s.routing(ctx, record, maps.Clone(attrs.tags)); hub != nil {
+			return hub
+		}
+	}
+	return s.hub(ctx, record.Level)
+}
+
+// hub returns the hub capturing records at level: the hub set for level with
+// WithLevelHub, else the hub in ctx, else the current hub.
+func (s *SentryHandler) hub(ctx context.Context, level slog.Level) *sentry.Hub {
+	if hub, ok := s.levelHubs[level]; ok {
+		return hub
+	}
+	if hub := sentry.GetHubFromContext(ctx); hub != nil {
+		return hub
+	}
+	return sentry.CurrentHub()
+}
+
+// buildEvent builds the Sentry event at level for record and its collected
+// attrs: an exception for ModeException, else a message.
+func (s *SentryHandler) buildEvent(ctx context.Context, hub *sentry.Hub, record slog.Record, attrs recordAttrs, mode CaptureMode, level sentry.Level) *sentry.Event {
+	attachStacktrace := s.attachStacktrace
+	maxErrorDepth := defaultMaxErrorDepth
+	if hub != nil {
+		if client := hub.Client(); client != nil {
+			attachStacktrace = attachStacktrace || client.Options().AttachStacktrace
+			maxErrorDepth = client.Options().MaxErrorDepth
+		}
+	}
+	if s.stacktraceMinLevel != nil {
+		attachStacktrace = record.Level >= *s.stacktraceMinLevel
+	}
+
+	exception := mode == ModeException
+	if exception && attrs.err != nil && s.errorTypeTag != "" {
+		attrs.tags[s.errorTypeTag] = fmt.Sprintf("%T", rootError(attrs.err))
+	}
+	// Only now all tags are set, including the error type tag.
+	if overflow := limitTags(attrs.tags, s.maxTags); len(overflow) > 0 && !s.disableContext {
+		attrs.context[tagsOverflowKey] = overflow
+	}
+
+	event := sentry.NewEvent()
+	event.Level = level
+	event.Logger = attrs.logger
+	event.Environment = s.environment
+	event.Dist = s.dist
+	if attrs.dist != "" {
+		event.Dist = attrs.dist
+	}
+	event.User = attrs.user
+	event.Request = attrs.request
+	event.Timestamp = record.Time
+	if event.Timestamp.IsZero() {
+		event.Timestamp = s.now()
+	}
+	event.Timestamp = event.Timestamp.UTC()
+	if exception {
+		msg := s.message(ctx, record, attrs.err)
+		event.SetException(SlogError{msg: msg, err: attrs.err}, maxErrorDepth)
+		if joined := joinedExceptions(attrs.err, maxErrorDepth); len(joined) > 0 {
+			// The joined errors precede the error joining them, which is last.
+			event.Exception = append(joined, event.Exception...)
+		}
+		if prefix, _, found := strings.Cut(msg, ": "); attrs.err == nil && found && prefix != "" {
+			// Without an error, the message prefix is the best type to group by.
+			event.Exception[len(event.Exception)-1].Type = prefix
+		}
+		handled := s.mechanismHandled
+		event.Exception[len(event.Exception)-1].Mechanism = &sentry.Mechanism{
+			Type:    s.mechanismType,
+			Handled: &handled,
+		}
+		if s.errorChainBreadcrumbs && attrs.err != nil {
+			event.Breadcrumbs = errorChainBreadcrumbs(attrs.err, event.Timestamp)
+		}
+	} else {
+		event.Message = s.message(ctx, record, attrs.err)
+		if s.groupInMessage && len(s.groups) > 0 {
+			event.Message = "[" + strings.Join(s.groups, ".") + "] " + event.Message
+		}
+		if attachStacktrace {
+			event.Threads = []sentry.Thread{{Stacktrace: sentry.NewStacktrace(), Current: true}}
+		}
+		if s.sourceLocation && record.PC != 0 {
+			// Group messages by the function logging them.
+			event.Transaction = sourceFrame(record.PC).Function
+		}
+	}
+
+	if s.maxContextSize > 0 {
+		attrs.context = trimData(attrs.context, s.maxContextSize)
+		for name, section := range attrs.sections {
+			attrs.sections[name] = trimData(section, s.maxContextSize)
+		}
+	}
+	if s.useExtra {
+		if !s.disableContext {
+			for key, value := range attrs.context {
+				event.Extra[key] = value
+			}
+		}
+		for name, section := range attrs.sections {
+			event.Extra[name] = section
+		}
+	} else {
+		if len(attrs.context) > 0 && !s.disableContext {
+			event.Contexts["slog"] = attrs.context
+		}
+		for name, section := range attrs.sections {
+			event.Contexts[name] = section
+		}
+		if exception && s.errorExtras {
+			if !s.disableContext {
+				for key, value := range attrs.context {
+					event.Extra[key] = value
 				}
 			}
+			for name, section := range attrs.sections {
+				event.Extra[name] = section
+			}
+		}
+	}
+	for key, value := range attrs.extra {
+		event.Extra[key] = value
+	}
+	for key, value := range attrs.tags {
+		event.Tags[key] = value
+	}
+	event.Fingerprint = attrs.fingerprint
+	if event.Fingerprint == nil && s.messageAsFingerprint {
+		event.Fingerprint = []string{record.Message}
+	}
+	if s.eventModifier != nil {
+		s.eventModifier(ctx, event)
+	}
+
+	return trimEventFrames(event, nil)
+}
+
+// message returns the message of the event of record with err, formatted
+// with the message formatter if any.
+func (s *SentryHandler) message(ctx context.Context, record slog.Record, err error) string {
+	if s.messageFormatter != nil {
+		return s.messageFormatter(ctx, record, err)
+	}
+	return record.Message
+}
+
+// capture sends event to hub, unless the before capture hook drops it, or
+// buffers it when batching.
+func (s *SentryHandler) capture(ctx context.Context, hub *sentry.Hub, event *sentry.Event, record slog.Record, mode CaptureMode) {
+	if s.beforeCapture != nil {
+		event = s.beforeCapture(ctx, event, record)
+	}
+	if event != nil && s.batcher != nil {
+		s.batcher.add(ctx, hub, event, record, mode)
+		return
+	}
+	s.send(ctx, hub, event, record, mode)
+}
+
+// send sends event, unless nil, to hub and reports the result to the on
+// capture hook and the event ID carrier of ctx.
+func (s *SentryHandler) send(ctx context.Context, hub *sentry.Hub, event *sentry.Event, record slog.Record, mode CaptureMode) {
+	// The hub returns a nil event ID when the Sentry client drops the event,
+	// e.g. by sampling, an event processor or BeforeSend, so the on capture
+	// hook sees every drop and never reports an event Sentry discarded.
+	var eventID *sentry.EventID
+	if event != nil && (len(s.eventProcessors) > 0 || s.modifyScope != nil) {
+		// A clone rather than WithScope, as goroutines pushing and popping
+		// scopes on a shared hub, e.g. the current hub, get each other's.
+		hub = hub.Clone()
+		for _, processor := range s.eventProcessors {
+			hub.Scope().AddEventProcessor(processor)
+		}
+		if s.modifyScope != nil {
+			s.modifyScope(hub.Scope(), ctx, record)
+		}
+	}
+	if event != nil {
+		eventID = hub.CaptureEvent(event)
+	}
+	if s.onCapture != nil {
+		s.onCapture(ctx, record, mode, eventID)
+	}
+	if carrier, ok := ctx.Value(eventIDKey{}).(*eventIDCarrier); ok && eventID != nil {
+		carrier.set(eventID)
+	}
+}
+
+// ResetState clears the state the handler keeps between records: the
+// window of WithRateLimit, the errors seen by WithErrorDedup, the warnings
+// counted by WithWarnEscalation and the one-time warning of a nil hub. The
+// state is shared with the handlers derived with WithAttrs and WithGroup, so
+// it clears theirs too. It is meant to isolate tests sharing a handler, and
+// is not needed in production.
+func (s *SentryHandler) ResetState() {
+	if s.rateLimiter != nil {
+		s.rateLimiter.reset()
+	}
+	if s.errorDeduper != nil {
+		s.errorDeduper.reset()
+	}
+	if s.warnEscalator != nil {
+		s.warnEscalator.reset()
+	}
+	if s.nilHubWarning != nil {
+		s.nilHubWarning.reset()
+	}
+}
+
+// ignores reports whether err matches an error of WithIgnoreErrors or a type
+// of WithIgnoreErrorTypes.
+func (s *SentryHandler) ignores(err error) bool {
+	if err == nil {
+		return false
+	}
+	for _, target := range s.ignoreErrors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	for _, typ := range s.ignoreErrorTypes {
+		if errors.As(err, reflect.New(typ).Interface()) {
 			return true
+		}
+	}
+	return false
+}
+
+// allow reports whether a record with err is captured: it is not a
+// duplicate and the rate limit allows another capture.
+func (s *SentryHandler) allow(err error) bool {
+	now := s.now()
+	rateAllows := func() bool {
+		return s.rateLimiter == nil || s.rateLimiter.allow(now)
+	}
+	if s.errorDeduper != nil && err != nil {
+		return s.errorDeduper.allow(err, now, rateAllows)
+	}
+	return rateAllows()
+}
+
+// belowWarnThreshold reports whether record is a warning, or between the
+// warning and error levels, that did not reach the threshold of
+// WithWarnEscalation yet.
+func (s *SentryHandler) belowWarnThreshold(record slog.Record) bool {
+	return s.warnEscalator != nil && baseLevel(record.Level) == slog.LevelWarn &&
+		!s.warnEscalator.escalate(record.Message, s.now())
+}
+
+// addBreadcrumb adds event to the scope of hub as a breadcrumb, rather than
+// capturing it, with the attributes of its slog context, or its extra data
+// with WithUseExtra, as data.
+func (s *SentryHandler) addBreadcrumb(hub *sentry.Hub, event *sentry.Event) {
+	data := event.Contexts["slog"]
+	if s.useExtra {
+		data = event.Extra
+	}
+	hub.AddBreadcrumb(&sentry.Breadcrumb{
+		Category:  "slog",
+		Message:   event.Message,
+		Data:      trimData(data, s.maxBreadcrumbDataSize),
+		Level:     event.Level,
+		Timestamp: event.Timestamp,
+	}, nil)
+}
+
+// captures reports whether the handler captures records at level.
+func (s *SentryHandler) captures(level slog.Level) bool {
+	return s.allLevels || slices.Contains(s.levels, level) || slices.Contains(s.sendAsLogs, level)
+}
+
+// logLevel returns the Sentry level of a record sent as a log, see
+// WithSendAsLogs: the level of the nearest slog level at or below level.
+func logLevel(level slog.Level) sentry.Level {
+	switch baseLevel(level) {
+	case slog.LevelError:
+		return sentry.LevelError
+	case slog.LevelWarn:
+		return sentry.LevelWarning
+	case slog.LevelInfo:
+		return sentry.LevelInfo
+	default:
+		return sentry.LevelDebug
+	}
+}
+
+// panics reports whether the handler panics for records at level.
+func (s *SentryHandler) panics(level slog.Level) bool {
+	return s.panicLevel != nil && level >= *s.panicLevel
+}
+
+// collectAttrs collects the stored and record attributes of record, on top
+// of the context of the context provider, the user from the context, the
+// tags of the tag func and the registered context tags.
+func (s *SentryHandler) collectAttrs(ctx context.Context, record slog.Record) recordAttrs {
+	// Most attributes end up in the context, so sizing it for all of them
+	// avoids growing it for wide records. Tags are few, so the tags map is not.
+	size := record.NumAttrs() + len(s.storedAttrs)
+	if s.disableContext {
+		size = 0
+	}
+	attrs := recordAttrs{
+		context: make(map[string]any, size),
+		tags:    map[string]string{},
+	}
+	if s.contextProvider != nil && !s.disableContext {
+		for key, value := range s.contextProvider(ctx) {
+			attrs.context[key] = value
+		}
+	}
+	if s.userFromContext != nil {
+		if user := s.userFromContext(ctx); user != nil {
+			attrs.user = copyUser(*user)
+		}
+	}
+	if s.tagFunc != nil {
+		for key, value := range s.tagFunc(ctx, record) {
+			attrs.tags[key] = value
+		}
+	}
+	if s.contextTags != nil {
+		s.contextTags.collect(ctx, func(tagName string, value any) {
+			attrs.tags[tagName] = s.stringValue(slog.AnyValue(value))
 		})
+	}
+	for _, stored := range s.storedAttrs {
+		s.handleAttr(&attrs, stored.groups, stored.attr)
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		if attr, ok := s.prepareAttr(s.groups, attr); ok {
+			s.handleAttr(&attrs, s.groups, attr)
+		}
+		return true
+	})
+	if s.sourceLocation && record.PC != 0 && !s.disableContext {
+		frame := sourceFrame(record.PC)
+		attrs.context[slog.SourceKey] = map[string]any{
+			"function": frame.Function,
+			"file":     frame.File,
+			"line":     frame.Line,
+		}
+	}
+	if s.name != "" {
+		attrs.tags[loggerNameTag] = s.name
+	}
+	if s.levelTag {
+		attrs.tags[levelTag] = record.Level.String()
+	}
+	if s.groupPathTag != "" && len(s.groups) > 0 {
+		attrs.tags[s.groupPathTag] = strings.Join(s.groups, ".")
+	}
+	if attrs.droppedAttrs > 0 {
+		attrs.context[droppedAttrsKey] = attrs.droppedAttrs
+	}
+	return attrs
+}
 
-		hub.WithScope(func(scope *sentry.Scope) {
-			if len(slogContext) > 0 {
-				scope.SetContext("slog", slogContext)
-			}
+// tagName returns the tag name for the key of a tag attribute: the key
+// without the first tag prefix it starts with. It reports false for other
+// attributes.
+func (s *SentryHandler) tagName(key string) (string, bool) {
+	for _, prefix := range s.tagPrefixes {
+		if prefix != "" && strings.HasPrefix(key, prefix) {
+			return strings.TrimPrefix(key, prefix), true
+		}
+	}
+	return "", false
+}
+
+// prepareAttr resolves attr and applies the ReplaceAttr function to it, like
+// the slog handlers do. It reports false when the attribute is discarded.
+func (s *SentryHandler) prepareAttr(groups []string, attr slog.Attr) (slog.Attr, bool) {
+	attr.Value = resolve(attr.Value)
+	if s.replaceAttr != nil && attr.Value.Kind() != slog.KindGroup {
+		attr = s.replaceAttr(groups, attr)
+		attr.Value = resolve(attr.Value)
+	}
+	if s.groupTagNamespace && len(groups) > 0 && attr.Value.Kind() != slog.KindGroup {
+		attr.Key = s.namespaceTagKey(groups, attr)
+	}
+	return attr, attr.Key != ""
+}
 
-			switch record.Level {
-			case slog.LevelError:
-				sentry.CaptureException(SlogError{msg: record.Message, err: err})
-			case slog.LevelDebug, slog.LevelInfo, slog.LevelWarn:
-				sentry.CaptureMessage(record.Message)
+// namespaceTagKey returns the key of a tag attribute in groups with the
+// group path added to the tag name, e.g. "tag_http.method" for the key
+// "tag_method" in the group "http", or "http.method" for Tag("method", ...).
+// Other keys are returned unchanged.
+func (s *SentryHandler) namespaceTagKey(groups []string, attr slog.Attr) string {
+	key := attr.Key
+	if attr.Value.Kind() == slog.KindAny {
+		if _, ok := attr.Value.Any().(tagValue); ok {
+			return strings.Join(groups, ".") + "." + key
+		}
+	}
+	for _, prefix := range s.tagPrefixes {
+		if prefix != "" && strings.HasPrefix(key, prefix) {
+			return prefix + strings.Join(groups, ".") + "." + strings.TrimPrefix(key, prefix)
+		}
+	}
+	return key
+}
 
+// storedAttr is an attribute added with WithAttrs, in the groups it was
+// added in.
+type storedAttr struct {
+	attr   slog.Attr
+	groups []string
+}
+
+// recordAttrs collects what the attributes of a record add to its event.
+type recordAttrs struct {
+	err         error
+	fingerprint []string
+	context     map[string]any
+	tags        map[string]string
+	level       sentry.Level
+	logger      string
+	skip        bool
+	user        sentry.User
+	request     *sentry.Request
+	dist        string
+
+	monitorSlug   string
+	monitorStatus string
+
+	// sections are the context sections of the groups, see
+	// WithGroupAsContextSection.
+	sections map[string]map[string]any
+	// extra are the attributes of WithExtraKeys.
+	extra map[string]any
+
+	// contextAttrs and droppedAttrs count the attributes added to and
+	// dropped from the context.
+	contextAttrs int
+	droppedAttrs int
+}
+
+// handleAttr adds attr, in groups, to attrs. The stored attributes are
+// handled in the order they were added, before the record attributes, so the
+// last attribute setting a tag, context key or the fingerprint wins: a record
+// attribute overrides a stored one. Fingerprints are replaced, not joined.
+func (s *SentryHandler) handleAttr(attrs *recordAttrs, groups []string, attr slog.Attr) {
+	if m, ok := attr.Value.Any().(meta); ok && attr.Key == metaKey {
+		attrs.level, attrs.logger = m.level, m.logger
+	} else if _, ok := attr.Value.Any().(logValuePanic); ok {
+		// Tag the keys of panicking LogValuers, so they can be found and fixed.
+		if failed := attrs.tags[attrErrorTag]; failed != "" {
+			attrs.tags[attrErrorTag] = failed + "," + attr.Key
+		} else {
+			attrs.tags[attrErrorTag] = attr.Key
+		}
+		if !s.disableContext {
+			s.contextSection(attrs, groups)[attr.Key] = s.stringValue(attr.Value)
+		}
+	} else if value, ok := attr.Value.Any().(tagValue); ok {
+		attrs.tags[attr.Key] = s.stringValue(slog.StringValue(string(value)))
+	} else if id, ok := attr.Value.Any().(userIDValue); ok {
+		attrs.user.ID = string(id)
+	} else if attr.Key == skipKey {
+		attrs.skip, _ = strconv.ParseBool(attr.Value.String())
+	} else if attr.Key == monitorSlugKey {
+		attrs.monitorSlug = attr.Value.String()
+	} else if attr.Key == monitorStatusKey {
+		attrs.monitorStatus = attr.Value.String()
+	} else if attr.Key == distKey {
+		attrs.dist = attr.Value.String()
+	} else if attr.Key == fingerprintKey {
+		attrs.fingerprint = fingerprintFromValue(attr.Value, s.fingerprintSep)
+	} else if errs, ok := validationErrorsFromAttr(attr); ok {
+		section := make(map[string]any, len(errs))
+		for field, msg := range errs {
+			section[field] = truncate(msg, s.maxValueLength)
+		}
+		if attrs.sections == nil {
+			attrs.sections = map[string]map[string]any{}
+		}
+		attrs.sections[validationSection] = section
+		attrs.tags[validationTag] = "true"
+	} else if name, value, ok := measurementFromAttr(attr); ok {
+		if attrs.sections == nil {
+			attrs.sections = map[string]map[string]any{}
+		}
+		if attrs.sections[measurementsSection] == nil {
+			attrs.sections[measurementsSection] = map[string]any{}
+		}
+		attrs.sections[measurementsSection][name] = value
+	} else if request, ok := requestFromAttr(attr); ok {
+		attrs.request = request
+	} else if s.userPrefix != "" && strings.HasPrefix(attr.Key, s.userPrefix) {
+		setUserField(&attrs.user, strings.TrimPrefix(attr.Key, s.userPrefix), s.stringValue(attr.Value))
+	} else if s.tagGroup != "" && attr.Key == s.tagGroup && attr.Value.Kind() == slog.KindGroup {
+		for _, tag := range attr.Value.Group() {
+			attrs.tags[tag.Key] = s.stringValue(resolve(tag.Value))
+		}
+	} else if label, ok := s.tagBucket(attr); ok {
+		attrs.tags[attr.Key] = label
+	} else if name, ok := s.tagName(attr.Key); ok {
+		attrs.tags[name] = s.stringValue(attr.Value)
+	} else if s.groupAsContextSection && len(groups) == 0 && attr.Value.Kind() == slog.KindGroup {
+		// A top-level group attribute is a section of its own.
+		sectionGroups := []string{attr.Key}
+		for _, member := range attr.Value.Group() {
+			if member, ok := s.prepareAttr(sectionGroups, member); ok {
+				s.handleAttr(attrs, sectionGroups, member)
 			}
+		}
+	} else if err, ok := errorSliceFromAttr(attr); ok {
+		attrs.err = err
+	} else if !s.ignoresKey(attr.Key) {
+		if s.disableContext {
+			return
+		}
+		if s.maxAttrs > 0 && attrs.contextAttrs >= s.maxAttrs {
+			attrs.droppedAttrs++
+			return
+		}
+		attrs.contextAttrs++
+		context := s.contextSection(attrs, groups)
+		if slices.Contains(s.extraKeys, attr.Key) {
+			if attrs.extra == nil {
+				attrs.extra = map[string]any{}
+			}
+			context = attrs.extra
+		}
+		if attr.Value.Kind() == slog.KindGroup {
+			context[attr.Key] = s.groupValue(groups, attr, 0)
+		} else {
+			context[attr.Key] = s.contextValue(attr.Value)
+		}
+		if attr.Value.Kind() == slog.KindDuration {
+			// Milliseconds as a number, so that Sentry can filter on it.
+			context[attr.Key+durationMsSuffix] = float64(attr.Value.Duration()) / float64(time.Millisecond)
+		}
+	} else if attr.Key == shortErrKey || attr.Key == longErrKey {
+		var ok bool
+		attrs.err, ok = attr.Value.Any().(error)
+		if !ok && s.stringErrors && attr.Value.Kind() == slog.KindString && attr.Value.String() != "" {
+			attrs.err, ok = errors.New(attr.Value.String()), true
+		}
+		if !ok && !s.disableContext {
+			s.contextSection(attrs, groups)[attr.Key] = s.stringValue(attr.Value)
+		}
+	}
+}
+
+// ignoresKey reports whether key is one of slog's built-in keys or an error
+// key, which are not added to the context as is, unless a built-in key is
+// kept with WithKeepDefaultKeys.
+func (s *SentryHandler) ignoresKey(key string) bool {
+	if key == shortErrKey || key == longErrKey {
+		return true
+	}
+	return slices.Contains(slogDefaultKeys, key) && !slices.Contains(s.keepDefaultKeys, key)
+}
+
+// contextSection returns the context of attributes in groups: the section
+// of the top-level group when WithGroupAsContextSection is enabled, else the
+// slog context.
+func (s *SentryHandler) contextSection(attrs *recordAttrs, groups []string) map[string]any {
+	if !s.groupAsContextSection || len(groups) == 0 {
+		return attrs.context
+	}
+	section, ok := attrs.sections[groups[0]]
+	if !ok {
+		if attrs.sections == nil {
+			attrs.sections = map[string]map[string]any{}
+		}
+		section = map[string]any{}
+		attrs.sections[groups[0]] = section
+	}
+	return section
+}
+
+// panic calls the configured panic handler with the context of the record,
+// or panics with err by default.
+func (s *SentryHandler) panic(ctx context.Context, err error) {
+	if s.panicHandler != nil {
+		s.panicHandler(ctx, err)
+		return
+	}
+	panic(err)
+}
+
+// errorChainBreadcrumbs returns a breadcrumb for every error in the chain
+// of err, from the deepest error to err itself, so the event shows how the
+// cause was wrapped.
+func errorChainBreadcrumbs(err error, timestamp time.Time) []*sentry.Breadcrumb {
+	var breadcrumbs []*sentry.Breadcrumb
+	for ; err != nil; err = errors.Unwrap(err) {
+		breadcrumbs = append(breadcrumbs, &sentry.Breadcrumb{
+			Type:      "error",
+			Category:  "slog.error",
+			Message:   err.Error(),
+			Data:      map[string]any{"type": fmt.Sprintf("%T", err)},
+			Level:     sentry.LevelError,
+			Timestamp: timestamp,
 		})
 	}
+	slices.Reverse(breadcrumbs)
+	return breadcrumbs
+}
 
-	return s.Handler.Handle(ctx, record)
+// rootError returns the deepest error wrapped by err.
+func rootError(err error) error {
+	for {
+		unwrapped := errors.Unwrap(err)
+		if unwrapped == nil {
+			return err
+		}
+		err = unwrapped
+	}
+}
+
+// limitTags removes the tags exceeding max from tags and returns them. The
+// tags kept are the first max in key order. A max of 0 or less is no limit.
+func limitTags(tags map[string]string, max int) map[string]string {
+	if max <= 0 || len(tags) <= max {
+		return nil
+	}
+
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	overflow := make(map[string]string, len(keys)-max)
+	for _, key := range keys[max:] {
+		overflow[key] = tags[key]
+		delete(tags, key)
+	}
+	return overflow
+}
+
+// fingerprintFromValue returns the fingerprint from the value of a fingerprint
+// attribute, which is either a string separated by sep or a []string. An
+// empty sep does not split the string.
+func fingerprintFromValue(value slog.Value, sep string) []string {
+	var parts []string
+	if v, ok := value.Any().([]string); ok {
+		parts = v
+	} else if sep != "" {
+		parts = strings.Split(value.String(), sep)
+	} else {
+		parts = []string{value.String()}
+	}
+
+	fingerprint := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			fingerprint = append(fingerprint, part)
+		}
+	}
+	return fingerprint
 }
 
 // WithAttrs returns a new SentryHandler whose attributes consists.
 func (s *SentryHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return NewSentryHandler(s.Handler.WithAttrs(attrs), s.levels)
+	c := s.clone(s.Handler.WithAttrs(attrs))
+	c.storedAttrs = slices.Clip(s.storedAttrs)
+	for _, attr := range attrs {
+		if attr, ok := s.prepareAttr(s.groups, attr); ok {
+			c.storedAttrs = append(c.storedAttrs, storedAttr{attr: attr, groups: s.groups})
+			c.storedMonitor = c.storedMonitor || attr.Key == monitorSlugKey
+		}
+	}
+	return c
 }
 
 // WithGroup returns a new SentryHandler whose group consists.
+// An empty name returns the handler itself, as slog.Handler requires.
 func (s *SentryHandler) WithGroup(name string) slog.Handler {
-	return NewSentryHandler(s.Handler.WithGroup(name), s.levels)
+	if name == "" {
+		return s
+	}
+	c := s.clone(s.Handler.WithGroup(name))
+	c.groups = append(slices.Clip(s.groups), name)
+	return c
+}
+
+// clone returns a copy of the SentryHandler, with its options, wrapping handler.
+func (s *SentryHandler) clone(handler slog.Handler) *SentryHandler {
+	c := *s
+	c.Handler = handler
+	return &c
 }
@@ -1,13 +1,90 @@
 package slogsentry
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/getsentry/sentry-go"
 )
 
+// transportMock is a sentry.Transport that records events and flushes.
+type transportMock struct {
+	mu            sync.Mutex
+	events        []*sentry.Event
+	flushes       int
+	flushTimeouts []time.Duration
+}
+
+func (t *transportMock) Configure(sentry.ClientOptions) {}
+
+func (t *transportMock) SendEvent(event *sentry.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = append(t.events, event)
+}
+
+func (t *transportMock) Flush(timeout time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.flushes++
+	t.flushTimeouts = append(t.flushTimeouts, timeout)
+	return true
+}
+
+func (t *transportMock) Events() []*sentry.Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*sentry.Event(nil), t.events...)
+}
+
+func (t *transportMock) Flushes() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.flushes
+}
+
+func (t *transportMock) FlushTimeouts() []time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]time.Duration(nil), t.flushTimeouts...)
+}
+
+// newTestHub returns a hub that sends to a transportMock.
+func newTestHub(t testing.TB, opts sentry.ClientOptions) (*sentry.Hub, *transportMock) {
+	t.Helper()
+	transport := &transportMock{}
+	opts.Transport = transport
+	client, err := sentry.NewClient(opts)
+	if err != nil {
+		t.Fatalf("error from sentry.NewClient: %s", err)
+	}
+	return sentry.NewHub(client, sentry.NewScope()), transport
+}
+
+// newTestContext returns a context carrying a hub that sends to a transportMock.
+func newTestContext(t testing.TB, opts sentry.ClientOptions) (context.Context, *transportMock) {
+	t.Helper()
+	hub, transport := newTestHub(t, opts)
+	return sentry.SetHubOnContext(context.Background(), hub), transport
+}
+
+// newTestHandler returns a SentryHandler which discards local output.
+func newTestHandler(levels []slog.Level, opts ...Option) *SentryHandler {
+	return NewSentryHandler(slog.NewTextHandler(io.Discard, nil), levels, opts...)
+}
+
 func TestSlogErrorErrorMethod(t *testing.T) {
 	tests := []struct {
 		input        SlogError
@@ -26,6 +103,42 @@ func TestSlogErrorErrorMethod(t *testing.T) {
 	}
 }
 
+// verboseError is an error with a verbose %+v form, like errors with stacks.
+type verboseError struct{}
+
+func (verboseError) Error() string { return "the error" }
+
+func (e verboseError) Format(f fmt.State, verb rune) {
+	if verb == 'v' && f.Flag('+') {
+		_, _ = io.WriteString(f, "the error\nstack")
+		return
+	}
+	_, _ = io.WriteString(f, e.Error())
+}
+
+func TestSlogErrorFormat(t *testing.T) {
+	tests := []struct {
+		format       string
+		input        SlogError
+		expectOutput string
+	}{
+		{"%v", SlogError{msg: "the message", err: verboseError{}}, "the message: the error"},
+		{"%s", SlogError{msg: "the message", err: verboseError{}}, "the message: the error"},
+		{"%q", SlogError{msg: "the message"}, `"the message"`},
+		{"%+v", SlogError{msg: "the message", err: verboseError{}}, "the message: the error\nstack"},
+		{"%+v", SlogError{err: verboseError{}}, "the error\nstack"},
+		{"%+v", SlogError{msg: "the message", err: errors.New("the error")}, "the message: the error"},
+		{"%+v", SlogError{msg: "the message"}, "the message"},
+	}
+
+	for i, test := range tests {
+		output := fmt.Sprintf(test.format, test.input)
+		if output != test.expectOutput {
+			t.Errorf("test %d: expect: %q, got: %q", i, test.expectOutput, output)
+		}
+	}
+}
+
 func TestHandleHandlesNilErrorAttr(t *testing.T) {
 	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", uintptr(0))
 	record.AddAttrs(slog.Any("some_attr", "yes"), slog.Any("error", nil))
@@ -36,3 +149,688 @@ func TestHandleHandlesNilErrorAttr(t *testing.T) {
 		t.Errorf("error from Handle: %s", err)
 	}
 }
+
+func TestHandleFingerprintAttr(t *testing.T) {
+	tests := []struct {
+		value             any
+		expectFingerprint []string
+	}{
+		{"db, timeout", []string{"db", "timeout"}},
+		{[]string{"db", "timeout"}, []string{"db", "timeout"}},
+		{"single", []string{"single"}},
+	}
+
+	for i, test := range tests {
+		ctx, transport := newTestContext(t, sentry.ClientOptions{})
+		handler := newTestHandler([]slog.Level{slog.LevelError})
+
+		record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+		record.AddAttrs(slog.Any("fingerprint", test.value))
+		if err := handler.Handle(ctx, record); err != nil {
+			t.Fatalf("test %d: error from Handle: %s", i, err)
+		}
+
+		events := transport.Events()
+		if len(events) != 1 {
+			t.Fatalf("test %d: expect 1 event, got: %d", i, len(events))
+		}
+		if !slices.Equal(events[0].Fingerprint, test.expectFingerprint) {
+			t.Errorf("test %d: expect: %q, got: %q", i, test.expectFingerprint, events[0].Fingerprint)
+		}
+		if _, ok := events[0].Contexts["slog"]["fingerprint"]; ok {
+			t.Errorf("test %d: expect fingerprint not in context", i)
+		}
+	}
+}
+
+func TestHandleStoredFingerprintAttr(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	handler := newTestHandler([]slog.Level{slog.LevelError}).WithAttrs([]slog.Attr{
+		slog.String("fingerprint", "request-key"),
+	})
+
+	for _, fingerprint := range []string{"", "record-key"} {
+		record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+		if fingerprint != "" {
+			record.AddAttrs(slog.String("fingerprint", fingerprint))
+		}
+		if err := handler.Handle(ctx, record); err != nil {
+			t.Fatalf("error from Handle: %s", err)
+		}
+	}
+
+	events := transport.Events()
+	if len(events) != 2 {
+		t.Fatalf("expect 2 events, got: %d", len(events))
+	}
+	if expect := []string{"request-key"}; !slices.Equal(events[0].Fingerprint, expect) {
+		t.Errorf("expect stored fingerprint: %q, got: %q", expect, events[0].Fingerprint)
+	}
+	if expect := []string{"record-key"}; !slices.Equal(events[1].Fingerprint, expect) {
+		t.Errorf("expect record fingerprint: %q, got: %q", expect, events[1].Fingerprint)
+	}
+}
+
+func TestHandleStoredErrorAttr(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	handler := newTestHandler([]slog.Level{slog.LevelError}, WithTagPrefix("tag_")).WithAttrs([]slog.Attr{
+		slog.String("tag_region", "eu"),
+		slog.Any("err", errors.New("the error")),
+	})
+
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	if err := handler.Handle(ctx, record); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("expect 1 event, got: %d", len(events))
+	}
+	exceptions := events[0].Exception
+	if len(exceptions) == 0 || exceptions[len(exceptions)-1].Value != "the message: the error" {
+		t.Errorf("expect the stored error as exception, got: %v", exceptions)
+	}
+	if tag := events[0].Tags["region"]; tag != "eu" {
+		t.Errorf("expect stored tag region %q, got: %q", "eu", tag)
+	}
+}
+
+func TestCaptureToEvent(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+
+	errRecord := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	errRecord.AddAttrs(
+		slog.Any("err", errors.New("the error")),
+		slog.String("some_attr", "yes"),
+		slog.String("tag_region", "eu"),
+		slog.String("fingerprint", "key"),
+	)
+	event := CaptureToEvent(ctx, errRecord, WithTagPrefix("tag_"))
+	if event == nil {
+		t.Fatal("expect an event for an error record")
+	}
+	if event.Level != sentry.LevelError {
+		t.Errorf("expect level %q, got: %q", sentry.LevelError, event.Level)
+	}
+	if len(event.Exception) == 0 || event.Exception[len(event.Exception)-1].Value != "the message: the error" {
+		t.Errorf("expect exception %q, got: %+v", "the message: the error", event.Exception)
+	}
+	if value := event.Contexts["slog"]["some_attr"]; value != "yes" {
+		t.Errorf("expect context some_attr %q, got: %v", "yes", value)
+	}
+	if tag := event.Tags["region"]; tag != "eu" {
+		t.Errorf("expect tag region %q, got: %q", "eu", tag)
+	}
+	if expect := []string{"key"}; !slices.Equal(event.Fingerprint, expect) {
+		t.Errorf("expect fingerprint %q, got: %q", expect, event.Fingerprint)
+	}
+
+	infoRecord := slog.NewRecord(time.Now(), slog.LevelInfo, "info message", 0)
+	event = CaptureToEvent(ctx, infoRecord)
+	if event == nil {
+		t.Fatal("expect an event for an info record")
+	}
+	if event.Message != "info message" || len(event.Exception) != 0 {
+		t.Errorf("expect message event %q, got: %q with %d exceptions", "info message", event.Message, len(event.Exception))
+	}
+	if _, ok := event.Contexts["slog"]; ok {
+		t.Error("expect no slog context without attributes")
+	}
+
+	event = CaptureToEvent(ctx, slog.NewRecord(time.Now(), slog.Level(2), "custom", 0))
+	if event == nil || event.Message != "custom" || event.Level != sentry.LevelInfo {
+		t.Errorf("expect an info message for a custom level, got: %+v", event)
+	}
+
+	noAttrs := WithFilter(func(ctx context.Context, record slog.Record) bool { return record.NumAttrs() > 0 })
+	if event := CaptureToEvent(ctx, infoRecord, noAttrs); event != nil {
+		t.Errorf("expect no event for a filtered record, got: %+v", event)
+	}
+	if event := CaptureToEvent(ctx, errRecord, noAttrs); event == nil {
+		t.Error("expect an event for a record passing the filter")
+	}
+	if event := CaptureToEvent(ContextWithCapture(ctx, false), errRecord); event != nil {
+		t.Errorf("expect no event with capturing disabled, got: %+v", event)
+	}
+
+	if n := len(transport.Events()); n != 0 {
+		t.Errorf("expect no events sent, got: %d", n)
+	}
+}
+
+func TestCaptureToEventNilHub(t *testing.T) {
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	event := CaptureToEvent(context.Background(), record, WithLevelHub(slog.LevelError, nil))
+	if event == nil || len(event.Exception) == 0 {
+		t.Fatalf("expect an exception event without a hub, got: %+v", event)
+	}
+}
+
+func TestHandleErrorWithoutErrorTypedByMessagePrefix(t *testing.T) {
+	tests := []struct {
+		msg        string
+		expectType string
+	}{
+		{"db: connection failed", "db"},
+		{"connection failed", "slogsentry.SlogError"},
+	}
+
+	for i, test := range tests {
+		event := CaptureToEvent(context.Background(), slog.NewRecord(time.Now(), slog.LevelError, test.msg, 0))
+		if event == nil || len(event.Exception) != 1 {
+			t.Fatalf("test %d: expect an event with 1 exception, got: %+v", i, event)
+		}
+		if exception := event.Exception[0]; exception.Type != test.expectType || exception.Value != test.msg {
+			t.Errorf("test %d: expect %q of type %q, got: %q of type %q", i, test.msg, test.expectType, exception.Value, exception.Type)
+		}
+	}
+}
+
+func TestHandleNestedSentryHandlersCaptureOnce(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	inner := newTestHandler([]slog.Level{slog.LevelInfo, slog.LevelError})
+	outer := NewSentryHandler(inner, []slog.Level{slog.LevelError})
+
+	if err := outer.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelError, "error", 0)); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+	if n := len(transport.Events()); n != 1 {
+		t.Errorf("expect 1 event, got: %d", n)
+	}
+
+	// The outer handler does not capture Info, so the inner one does.
+	if err := outer.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelInfo, "info", 0)); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+	if n := len(transport.Events()); n != 2 {
+		t.Errorf("expect 2 events, got: %d", n)
+	}
+}
+
+func TestHandleStructuredContext(t *testing.T) {
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	record.AddAttrs(slog.Any("request", map[string]any{"path": "/", "query": map[string]string{"q": "x"}}))
+
+	event := CaptureToEvent(context.Background(), record)
+	if event == nil {
+		t.Fatal("expect an event")
+	}
+	expect := map[string]any{"path": "/", "query": map[string]any{"q": "x"}}
+	if request := event.Contexts["slog"]["request"]; !reflect.DeepEqual(request, expect) {
+		t.Errorf("expect: %#v, got: %#v", expect, request)
+	}
+}
+
+// loopValuer is a slog.LogValuer that resolves to itself.
+type loopValuer struct{}
+
+func (v loopValuer) LogValue() slog.Value {
+	return slog.AnyValue(v)
+}
+
+// userValuer is a slog.LogValuer that resolves to a string.
+type userValuer struct{}
+
+func (userValuer) LogValue() slog.Value {
+	return slog.StringValue("user-7")
+}
+
+func TestHandleResolvesLogValuers(t *testing.T) {
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	record.AddAttrs(slog.Any("user", userValuer{}), slog.Any("loop", loopValuer{}))
+
+	done := make(chan *sentry.Event)
+	go func() {
+		done <- CaptureToEvent(context.Background(), record)
+	}()
+
+	var event *sentry.Event
+	select {
+	case event = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expect self-referential LogValuer to terminate")
+	}
+	if user := event.Contexts["slog"]["user"]; user != "user-7" {
+		t.Errorf("expect user %q, got: %v", "user-7", user)
+	}
+	if loop, _ := event.Contexts["slog"]["loop"].(string); !strings.Contains(loop, "LogValue called too many times") {
+		t.Errorf("expect loop to resolve to an error, got: %q", loop)
+	}
+}
+
+func TestHandleUsesRequestScopedHub(t *testing.T) {
+	globalCtx, globalTransport := newTestContext(t, sentry.ClientOptions{})
+	hub := sentry.GetHubFromContext(globalCtx).Clone()
+
+	// Like the sentryhttp middleware, set the request on a hub for the request.
+	request := httptest.NewRequest(http.MethodPost, "http://example.com/orders?id=7", nil)
+	request.Header.Set("User-Agent", "test")
+	hub.Scope().SetRequest(request)
+	ctx := sentry.SetHubOnContext(request.Context(), hub)
+
+	logger := slog.New(newTestHandler([]slog.Level{slog.LevelError}))
+	logger.ErrorContext(ctx, "the message", "err", errors.New("the error"))
+
+	events := globalTransport.Events()
+	if len(events) != 1 {
+		t.Fatalf("expect 1 event, got: %d", len(events))
+	}
+	if events[0].Request == nil {
+		t.Fatal("expect the request of the hub on the event")
+	}
+	if events[0].Request.URL != "http://example.com/orders" || events[0].Request.Method != http.MethodPost {
+		t.Errorf("expect POST http://example.com/orders, got: %s %s", events[0].Request.Method, events[0].Request.URL)
+	}
+	if events[0].Request.QueryString != "id=7" {
+		t.Errorf("expect query %q, got: %q", "id=7", events[0].Request.QueryString)
+	}
+}
+
+func TestHandleSkipAttr(t *testing.T) {
+	tests := []struct {
+		value        any
+		expectEvents int
+	}{
+		{true, 0},
+		{"true", 0},
+		{false, 1},
+		{"no", 1},
+	}
+
+	for i, test := range tests {
+		ctx, transport := newTestContext(t, sentry.ClientOptions{})
+		var local bytes.Buffer
+		handler := NewSentryHandler(slog.NewTextHandler(&local, nil), []slog.Level{slog.LevelError})
+
+		record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+		record.AddAttrs(slog.Any("sentry_skip", test.value))
+		if err := handler.Handle(ctx, record); err != nil {
+			t.Fatalf("test %d: error from Handle: %s", i, err)
+		}
+
+		events := transport.Events()
+		if len(events) != test.expectEvents {
+			t.Errorf("test %d: expect %d events, got: %d", i, test.expectEvents, len(events))
+		}
+		if len(events) > 0 {
+			if _, ok := events[0].Contexts["slog"]["sentry_skip"]; ok {
+				t.Errorf("test %d: expect skip attribute not in context", i)
+			}
+		}
+		if !strings.Contains(local.String(), "the message") {
+			t.Errorf("test %d: expect the record to be logged locally", i)
+		}
+	}
+}
+
+func TestHandleDurationAttr(t *testing.T) {
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	record.AddAttrs(slog.Duration("took", 1500*time.Microsecond))
+
+	event := CaptureToEvent(context.Background(), record)
+	if event == nil {
+		t.Fatal("expect an event")
+	}
+	if took := event.Contexts["slog"]["took"]; took != "1.5ms" {
+		t.Errorf("expect took %q, got: %v", "1.5ms", took)
+	}
+	if tookMs := event.Contexts["slog"]["took_ms"]; tookMs != 1.5 {
+		t.Errorf("expect took_ms %v, got: %v", 1.5, tookMs)
+	}
+}
+
+func TestHandleEventTimestampFromRecord(t *testing.T) {
+	location := time.FixedZone("UTC+2", 2*60*60)
+	recordTime := time.Date(2024, 1, 1, 14, 30, 15, 123456789, location)
+
+	event := CaptureToEvent(context.Background(), slog.NewRecord(recordTime, slog.LevelError, "the message", 0))
+	if event == nil {
+		t.Fatal("expect an event")
+	}
+	expect := time.Date(2024, 1, 1, 12, 30, 15, 123456789, time.UTC)
+	if event.Timestamp != expect {
+		t.Errorf("expect timestamp %s, got: %s", expect, event.Timestamp)
+	}
+}
+
+// recordingHandler is a slog.Handler recording the attributes of records.
+type recordingHandler struct {
+	slog.Handler
+	attrs [][]slog.Attr
+}
+
+func (h *recordingHandler) Handle(_ context.Context, record slog.Record) error {
+	var attrs []slog.Attr
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+	h.attrs = append(h.attrs, attrs)
+	return nil
+}
+
+func TestHandleFanOutRecordNotModified(t *testing.T) {
+	ctx, _ := newTestContext(t, sentry.ClientOptions{})
+	first := &recordingHandler{Handler: slog.NewTextHandler(io.Discard, nil)}
+	second := &recordingHandler{Handler: slog.NewTextHandler(io.Discard, nil)}
+	redact := func(_ []string, attr slog.Attr) slog.Attr {
+		return slog.String(attr.Key, "REDACTED")
+	}
+	handler := NewSentryHandler(first, []slog.Level{slog.LevelError}, WithReplaceAttr(redact), WithMaxValueLength(3))
+
+	// More attributes than a record stores inline, so copies share storage.
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	for n := 0; n < 8; n++ {
+		record.AddAttrs(slog.Int(fmt.Sprintf("attr%d", n), n))
+	}
+
+	// Fan out the same record, like a handler passing it to several handlers.
+	for _, h := range []slog.Handler{handler, second} {
+		if err := h.Handle(ctx, record); err != nil {
+			t.Fatalf("error from Handle: %s", err)
+		}
+	}
+
+	for name, h := range map[string]*recordingHandler{"wrapped": first, "other": second} {
+		if len(h.attrs) != 1 || len(h.attrs[0]) != 8 {
+			t.Fatalf("%s handler: expect 1 record with 8 attributes, got: %v", name, h.attrs)
+		}
+		for n, attr := range h.attrs[0] {
+			if attr.Key != fmt.Sprintf("attr%d", n) || attr.Value.Int64() != int64(n) {
+				t.Errorf("%s handler: expect attr%d=%d, got: %s", name, n, n, attr)
+			}
+		}
+	}
+}
+
+func TestWithGroupEmptyName(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	var groups [][]string
+	handler := newTestHandler([]slog.Level{slog.LevelError},
+		WithGroupPathTag("log_group"),
+		WithReplaceAttr(func(g []string, attr slog.Attr) slog.Attr {
+			groups = append(groups, g)
+			return attr
+		}),
+	)
+
+	if h := handler.WithGroup(""); h != handler {
+		t.Error("expect WithGroup with an empty name to return the handler")
+	}
+	grouped := handler.WithGroup("http").WithGroup("")
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	record.AddAttrs(slog.Int("n", 1))
+	if err := grouped.Handle(ctx, record); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("expect 1 event, got: %d", len(events))
+	}
+	if tag := events[0].Tags["log_group"]; tag != "http" {
+		t.Errorf("expect tag %q, got: %q", "http", tag)
+	}
+	if len(groups) != 1 || !slices.Equal(groups[0], []string{"http"}) {
+		t.Errorf("expect ReplaceAttr groups [http], got: %v", groups)
+	}
+}
+
+func TestResetState(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	handler := newTestHandler([]slog.Level{slog.LevelError},
+		WithRateLimit(1, time.Hour),
+		WithErrorDedup(time.Hour),
+	)
+	derived := handler.WithAttrs([]slog.Attr{slog.Int("n", 1)})
+
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	record.AddAttrs(slog.Any("err", errors.New("the error")))
+	for i, test := range []struct {
+		handler      slog.Handler
+		reset        bool
+		expectEvents int
+	}{
+		{handler, false, 1},
+		{handler, false, 1},
+		{handler, true, 2},
+		{derived, false, 2},
+		{derived, true, 3},
+	} {
+		if test.reset {
+			handler.ResetState()
+		}
+		if err := test.handler.Handle(ctx, record); err != nil {
+			t.Fatalf("test %d: error from Handle: %s", i, err)
+		}
+		if n := len(transport.Events()); n != test.expectEvents {
+			t.Errorf("test %d: expect %d events, got: %d", i, test.expectEvents, n)
+		}
+	}
+}
+
+// countingHandler is a slog.Handler counting the records by message, safe
+// for concurrent use. Its derived handlers are itself.
+type countingHandler struct {
+	slog.Handler
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (h *countingHandler) Handle(_ context.Context, record slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts[record.Message]++
+	return nil
+}
+
+func (h *countingHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *countingHandler) WithGroup(string) slog.Handler      { return h }
+
+func (h *countingHandler) Count(msg string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.counts[msg]
+}
+
+func TestHandleNilHubWarnsOnce(t *testing.T) {
+	inner := &countingHandler{Handler: slog.NewTextHandler(io.Discard, nil), counts: map[string]int{}}
+	handler := NewSentryHandler(inner, []slog.Level{slog.LevelError}, WithLevelHub(slog.LevelError, nil))
+	derived := handler.WithAttrs([]slog.Attr{slog.Int("n", 1)})
+
+	const goroutines = 50
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(h slog.Handler) {
+			defer wg.Done()
+			if err := h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)); err != nil {
+				t.Errorf("error from Handle: %s", err)
+			}
+		}([]slog.Handler{handler, derived}[i%2])
+	}
+	wg.Wait()
+
+	if n := inner.Count(nilHubMessage); n != 1 {
+		t.Errorf("expect 1 nil hub warning, got: %d", n)
+	}
+	if n := inner.Count("the message"); n != goroutines {
+		t.Errorf("expect %d records handled, got: %d", goroutines, n)
+	}
+
+	handler.ResetState()
+	if err := derived.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+	if n := inner.Count(nilHubMessage); n != 2 {
+		t.Errorf("expect another nil hub warning after ResetState, got: %d warnings", n)
+	}
+}
+
+func TestWithAttrsTagsAccumulate(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	logger := slog.New(newTestHandler([]slog.Level{slog.LevelError}, WithTagPrefix("tag_")))
+	base := logger.With("tag_a", "1")
+	first := base.With("tag_b", "2")
+	// A sibling derived from the same logger must not see the tags of first.
+	second := base.With("tag_c", "3")
+
+	first.ErrorContext(ctx, "first")
+	second.ErrorContext(ctx, "second")
+
+	events := transport.Events()
+	if len(events) != 2 {
+		t.Fatalf("expect 2 events, got: %d", len(events))
+	}
+	for i, expected := range []map[string]string{
+		{"a": "1", "b": "2"},
+		{"a": "1", "c": "3"},
+	} {
+		if tags := events[i].Tags; !reflect.DeepEqual(tags, expected) {
+			t.Errorf("event %d: expect tags %v, got: %v", i, expected, tags)
+		}
+	}
+}
+
+// panicValuer is a slog.LogValuer that panics.
+type panicValuer struct{}
+
+func (panicValuer) LogValue() slog.Value {
+	panic("broken valuer")
+}
+
+func TestHandlePanickingLogValuer(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	handler := newTestHandler([]slog.Level{slog.LevelError}).WithAttrs([]slog.Attr{slog.Any("account", panicValuer{})})
+
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	record.AddAttrs(slog.Any("user", panicValuer{}), slog.Any("ok", userValuer{}))
+	if err := handler.Handle(ctx, record); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("expect 1 event, got: %d", len(events))
+	}
+	if tag := events[0].Tags["_slog_attr_error"]; tag != "account,user" {
+		t.Errorf("expect tag _slog_attr_error %q, got: %q", "account,user", tag)
+	}
+	if value := events[0].Contexts["slog"]["user"]; value != "<LogValue panicked: broken valuer>" {
+		t.Errorf("expect placeholder for user, got: %v", value)
+	}
+	if value := events[0].Contexts["slog"]["ok"]; value != "user-7" {
+		t.Errorf("expect context ok %q, got: %v", "user-7", value)
+	}
+}
+
+func TestHandleConcurrentCapturesOnSharedHub(t *testing.T) {
+	hub, transport := newTestHub(t, sentry.ClientOptions{})
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+	handlers := make([]*SentryHandler, 4)
+	for i := range handlers {
+		name := fmt.Sprintf("handler%d", i)
+		handlers[i] = newTestHandler([]slog.Level{slog.LevelError},
+			WithTagPrefix("tag_"),
+			WithEventProcessor(func(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+				event.Tags["processor_"+name] = "yes"
+				return event
+			}),
+		)
+	}
+
+	const perHandler = 25
+	var wg sync.WaitGroup
+	for i, handler := range handlers {
+		for n := 0; n < perHandler; n++ {
+			wg.Add(1)
+			go func(i int, handler *SentryHandler) {
+				defer wg.Done()
+				record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+				record.AddAttrs(slog.String("tag_handler", fmt.Sprintf("handler%d", i)))
+				if err := handler.Handle(ctx, record); err != nil {
+					t.Errorf("error from Handle: %s", err)
+				}
+			}(i, handler)
+		}
+	}
+	wg.Wait()
+
+	events := transport.Events()
+	if len(events) != len(handlers)*perHandler {
+		t.Fatalf("expect %d events, got: %d", len(handlers)*perHandler, len(events))
+	}
+	for _, event := range events {
+		expected := map[string]string{"handler": event.Tags["handler"], "processor_" + event.Tags["handler"]: "yes"}
+		if !reflect.DeepEqual(event.Tags, expected) {
+			t.Errorf("expect tags %v, got: %v", expected, event.Tags)
+		}
+	}
+}
+
+func TestWillCapture(t *testing.T) {
+	// The wrapped handler is only enabled for errors.
+	inner := slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError})
+	tests := []struct {
+		handler *SentryHandler
+		level   slog.Level
+		expect  bool
+	}{
+		{NewSentryHandler(inner, []slog.Level{slog.LevelWarn}), slog.LevelWarn, true},
+		{NewSentryHandler(inner, []slog.Level{slog.LevelWarn}), slog.LevelError, false},
+		{NewSentryHandler(inner, nil), slog.LevelError, false},
+		{NewSentryHandler(inner, nil, WithAllLevels()), slog.LevelDebug, true},
+		{NewSentryHandler(inner, nil, WithPanicLevel(slog.LevelError)), slog.LevelError + 4, true},
+		{NewSentryHandler(inner, nil, WithSendAsLogs(slog.LevelInfo)), slog.LevelInfo, true},
+	}
+
+	for i, test := range tests {
+		if will := test.handler.WillCapture(test.level); will != test.expect {
+			t.Errorf("test %d: expect WillCapture(%s) %t, got: %t", i, test.level, test.expect, will)
+		}
+	}
+}
+
+func TestHandleRecordAttrsShadowStoredAttrs(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	handler := newTestHandler([]slog.Level{slog.LevelError}, WithTagPrefix("tag_")).
+		WithAttrs([]slog.Attr{slog.String("tag_tier", "free"), slog.String("tag_region", "eu"), slog.String("fingerprint", "first")}).
+		WithAttrs([]slog.Attr{slog.String("tag_tier", "gold"), slog.String("fingerprint", "second,part")})
+
+	tests := []struct {
+		attrs             []slog.Attr
+		expectTags        map[string]string
+		expectFingerprint []string
+	}{
+		{
+			nil,
+			map[string]string{"tier": "gold", "region": "eu"},
+			[]string{"second", "part"},
+		},
+		{
+			[]slog.Attr{slog.String("tag_tier", "platinum"), slog.String("fingerprint", "record")},
+			map[string]string{"tier": "platinum", "region": "eu"},
+			[]string{"record"},
+		},
+	}
+
+	for i, test := range tests {
+		record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+		record.AddAttrs(test.attrs...)
+		if err := handler.Handle(ctx, record); err != nil {
+			t.Fatalf("test %d: error from Handle: %s", i, err)
+		}
+
+		events := transport.Events()
+		if len(events) != i+1 {
+			t.Fatalf("test %d: expect %d events, got: %d", i, i+1, len(events))
+		}
+		if tags := events[i].Tags; !reflect.DeepEqual(tags, test.expectTags) {
+			t.Errorf("test %d: expect tags %v, got: %v", i, test.expectTags, tags)
+		}
+		if fingerprint := events[i].Fingerprint; !slices.Equal(fingerprint, test.expectFingerprint) {
+			t.Errorf("test %d: expect fingerprint %q, got: %q", i, test.expectFingerprint, fingerprint)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package slogsentry
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	// measurementPrefix is the key prefix of the attributes with the
+	// measurements of a record, e.g. measurement_db_ms.
+	measurementPrefix = "measurement_"
+	// measurementsSection is the context section of the measurements. The
+	// Sentry SDK has no measurements on error and message events, so they
+	// are sent as context instead.
+	measurementsSection = "measurements"
+)
+
+// measurementFromAttr returns the name and value of a measurement attribute
+// with a numeric value; durations are in milliseconds. It reports false for
+// other attributes.
+func measurementFromAttr(attr slog.Attr) (string, float64, bool) {
+	name, ok := strings.CutPrefix(attr.Key, measurementPrefix)
+	if !ok || name == "" {
+		return "", 0, false
+	}
+	switch attr.Value.Kind() {
+	case slog.KindInt64:
+		return name, float64(attr.Value.Int64()), true
+	case slog.KindUint64:
+		return name, float64(attr.Value.Uint64()), true
+	case slog.KindFloat64:
+		return name, attr.Value.Float64(), true
+	case slog.KindDuration:
+		return name, float64(attr.Value.Duration()) / float64(time.Millisecond), true
+	default:
+		return "", 0, false
+	}
+}
@@ -0,0 +1,36 @@
+package slogsentry
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+func TestMeasurementAttrs(t *testing.T) {
+	record := slog.NewRecord(time.Now(), slog.LevelWarn, "slow request", 0)
+	record.AddAttrs(
+		slog.Float64("measurement_db_ms", 12.5),
+		slog.Int("measurement_rows", 3),
+		slog.Duration("measurement_total", 1500*time.Microsecond),
+		slog.String("measurement_note", "cached"),
+	)
+
+	event := CaptureToEvent(context.Background(), record)
+	if event == nil {
+		t.Fatal("expect an event")
+	}
+	expected := sentry.Context{"db_ms": 12.5, "rows": 3.0, "total": 1.5}
+	if measurements := event.Contexts["measurements"]; !reflect.DeepEqual(measurements, expected) {
+		t.Errorf("expect measurements %v, got: %v", expected, measurements)
+	}
+	if value := event.Contexts["slog"]["measurement_note"]; value != "cached" {
+		t.Errorf("expect context measurement_note %q, got: %v", "cached", value)
+	}
+	if _, ok := event.Contexts["slog"]["measurement_db_ms"]; ok {
+		t.Error("expect no measurement_db_ms in the slog context")
+	}
+}
@@ -0,0 +1,106 @@
+package slogsentry
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strconv"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// CaptureMode is how a SentryHandler captures a record.
+type CaptureMode int
+
+const (
+	// ModeSkip does not capture the record.
+	ModeSkip CaptureMode = iota
+	// ModeMessage captures the record as a message event.
+	ModeMessage
+	// ModeException captures the record as an exception event.
+	ModeException
+	// ModeBreadcrumb adds the record as a breadcrumb to the scope of the
+	// hub, so that it shows on the next event captured.
+	ModeBreadcrumb
+)
+
+// String returns the name of the mode, e.g. "exception".
+func (m CaptureMode) String() string {
+	switch m {
+	case ModeSkip:
+		return "skip"
+	case ModeMessage:
+		return "message"
+	case ModeException:
+		return "exception"
+	case ModeBreadcrumb:
+		return "breadcrumb"
+	default:
+		return "CaptureMode(" + strconv.Itoa(int(m)) + ")"
+	}
+}
+
+// captureMode returns how record is captured, and at which Sentry level, by
+// its level, its collected attrs and the options. The strategy of
+// WithCaptureStrategy has the final say, except for skipped records, records
+// with ignored errors and records that panic.
+func (s *SentryHandler) captureMode(ctx context.Context, record slog.Record, attrs recordAttrs, panics bool) (CaptureMode, sentry.Level) {
+	if attrs.skip || s.ignores(attrs.err) {
+		return ModeSkip, ""
+	}
+
+	mode, level := ModeMessage, logLevel(record.Level)
+	mapped, named := s.levelNames[s.levelName(record.Level)]
+	// Levels between slog's own, e.g. with WithAllLevels, are captured like
+	// the nearest level below them.
+	base := baseLevel(record.Level)
+	asLog := !panics && slices.Contains(s.sendAsLogs, record.Level)
+	switch {
+	case asLog:
+	case !panics && named:
+		level = mapped
+		if (mapped == sentry.LevelError || mapped == sentry.LevelFatal) && attrs.err != nil {
+			mode = ModeException
+		}
+	case (base == slog.LevelError || panics) && attrs.err == nil && s.messageOnlyWhenNoError:
+		level = sentry.LevelError
+	case base == slog.LevelError || panics:
+		mode, level = ModeException, sentry.LevelError
+	case base == slog.LevelWarn && s.warnAsException && attrs.err != nil:
+		mode, level = ModeException, sentry.LevelWarning
+	default:
+		level = sentry.LevelInfo
+	}
+
+	if attrs.level != "" {
+		level = attrs.level
+	}
+	if mode == ModeMessage && s.alwaysException && !asLog {
+		mode = ModeException
+	}
+	if panics {
+		return mode, level
+	}
+	if s.belowWarnThreshold(record) {
+		mode = ModeBreadcrumb
+	}
+	if s.captureStrategy != nil {
+		mode = s.captureStrategy(ctx, record, mode)
+	}
+	return mode, level
+}
+
+// baseLevel returns the nearest of slog's levels at or below level, or
+// slog.LevelDebug below it.
+func baseLevel(level slog.Level) slog.Level {
+	switch {
+	case level >= slog.LevelError:
+		return slog.LevelError
+	case level >= slog.LevelWarn:
+		return slog.LevelWarn
+	case level >= slog.LevelInfo:
+		return slog.LevelInfo
+	default:
+		return slog.LevelDebug
+	}
+}
@@ -0,0 +1,21 @@
+package slogsentry
+
+import "testing"
+
+func TestCaptureModeString(t *testing.T) {
+	tests := []struct {
+		mode   CaptureMode
+		expect string
+	}{
+		{ModeSkip, "skip"},
+		{ModeMessage, "message"},
+		{ModeException, "exception"},
+		{ModeBreadcrumb, "breadcrumb"},
+		{CaptureMode(7), "CaptureMode(7)"},
+	}
+	for _, test := range tests {
+		if s := test.mode.String(); s != test.expect {
+			t.Errorf("expect %q for mode %d, got: %q", test.expect, int(test.mode), s)
+		}
+	}
+}
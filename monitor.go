@@ -0,0 +1,46 @@
+package slogsentry
+
+import (
+	"log/slog"
+
+	"github.com/getsentry/sentry-go"
+)
+
+const (
+	// monitorSlugKey is the key of the attribute making a record a cron
+	// check-in of the monitor with the slug of its value.
+	monitorSlugKey = "monitor_slug"
+	// monitorStatusKey is the key of the attribute setting the status of a
+	// check-in: "ok", "error" or "in_progress".
+	monitorStatusKey = "monitor_status"
+)
+
+// monitors reports whether record, or the stored attributes, has a monitor
+// slug attribute, which makes it a check-in whatever its level.
+func (s *SentryHandler) monitors(record slog.Record) bool {
+	if s.storedMonitor {
+		return true
+	}
+	found := false
+	record.Attrs(func(attr slog.Attr) bool {
+		found = attr.Key == monitorSlugKey
+		return !found
+	})
+	return found
+}
+
+// checkIn sends the cron check-in of record to hub. Without a valid status
+// attribute, records at the error level or above are failed check-ins,
+// others successful ones.
+func (s *SentryHandler) checkIn(hub *sentry.Hub, record slog.Record, attrs recordAttrs) {
+	status := sentry.CheckInStatus(attrs.monitorStatus)
+	switch status {
+	case sentry.CheckInStatusOK, sentry.CheckInStatusError, sentry.CheckInStatusInProgress:
+	default:
+		status = sentry.CheckInStatusOK
+		if record.Level >= slog.LevelError {
+			status = sentry.CheckInStatusError
+		}
+	}
+	hub.CaptureCheckIn(&sentry.CheckIn{MonitorSlug: attrs.monitorSlug, Status: status}, nil)
+}
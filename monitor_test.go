@@ -0,0 +1,76 @@
+package slogsentry
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+func TestMonitorCheckIn(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	handler := newTestHandler([]slog.Level{slog.LevelError})
+	job := handler.WithAttrs([]slog.Attr{slog.String("monitor_slug", "nightly-export")})
+
+	tests := []struct {
+		level        slog.Level
+		attrs        []slog.Attr
+		expectStatus sentry.CheckInStatus
+	}{
+		{slog.LevelInfo, []slog.Attr{slog.String("monitor_status", "in_progress")}, sentry.CheckInStatusInProgress},
+		{slog.LevelInfo, nil, sentry.CheckInStatusOK},
+		{slog.LevelError, nil, sentry.CheckInStatusError},
+		{slog.LevelError, []slog.Attr{slog.String("monitor_status", "ok")}, sentry.CheckInStatusOK},
+	}
+
+	for i, test := range tests {
+		record := slog.NewRecord(time.Now(), test.level, "export", 0)
+		record.AddAttrs(test.attrs...)
+		if err := job.Handle(ctx, record); err != nil {
+			t.Fatalf("test %d: error from Handle: %s", i, err)
+		}
+
+		events := transport.Events()
+		if len(events) != i+1 {
+			t.Fatalf("test %d: expect %d events, got: %d", i, i+1, len(events))
+		}
+		event := events[i]
+		if event.Type != "check_in" || event.CheckIn == nil {
+			t.Fatalf("test %d: expect a check-in, got event type %q", i, event.Type)
+		}
+		if slug := event.CheckIn.MonitorSlug; slug != "nightly-export" {
+			t.Errorf("test %d: expect monitor slug %q, got: %q", i, "nightly-export", slug)
+		}
+		if status := event.CheckIn.Status; status != test.expectStatus {
+			t.Errorf("test %d: expect status %q, got: %q", i, test.expectStatus, status)
+		}
+	}
+
+	// Records without a monitor slug are not check-ins.
+	if err := handler.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelError, "failed", 0)); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+	events := transport.Events()
+	if event := events[len(events)-1]; event.CheckIn != nil {
+		t.Errorf("expect an error event, got a check-in")
+	}
+}
+
+func TestMonitorCheckInSkipped(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	handler := newTestHandler([]slog.Level{slog.LevelError}, WithIgnoreErrors(context.Canceled))
+	job := handler.WithAttrs([]slog.Attr{slog.String("monitor_slug", "nightly-export")})
+
+	for _, attr := range []slog.Attr{slog.Bool("sentry_skip", true), slog.Any("err", context.Canceled)} {
+		record := slog.NewRecord(time.Now(), slog.LevelError, "export", 0)
+		record.AddAttrs(attr)
+		if err := job.Handle(ctx, record); err != nil {
+			t.Fatalf("%s: error from Handle: %s", attr.Key, err)
+		}
+	}
+	if events := transport.Events(); len(events) != 0 {
+		t.Errorf("expect no check-ins for skipped records and ignored errors, got: %d", len(events))
+	}
+}
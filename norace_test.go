@@ -0,0 +1,7 @@
+//go:build !race
+
+package slogsentry
+
+// raceEnabled reports whether the race detector is enabled, which changes
+// the allocations of the code under test.
+const raceEnabled = false
@@ -0,0 +1,683 @@
+package slogsentry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Option configures optional behaviour of a SentryHandler.
+type Option func(*SentryHandler)
+
+var (
+	defaultOptionsMu sync.RWMutex
+	defaultOpts      []Option
+)
+
+// SetDefaultOptions sets the options applied to every SentryHandler created
+// afterwards, e.g. to configure the tag prefix once for a large application.
+// The options passed to NewSentryHandler override the defaults. Calling
+// SetDefaultOptions without options clears the defaults.
+func SetDefaultOptions(opts ...Option) {
+	defaultOptionsMu.Lock()
+	defer defaultOptionsMu.Unlock()
+	defaultOpts = append([]Option(nil), opts...)
+}
+
+// defaultOptions returns the options set with SetDefaultOptions.
+func defaultOptions() []Option {
+	defaultOptionsMu.RLock()
+	defer defaultOptionsMu.RUnlock()
+	return defaultOpts
+}
+
+// WithPanicLevel makes the handler panic after handling a record logged at
+// or above level, similar to zap's DPanic and Panic levels. The record is
+// always captured and the hub is flushed before panicking, so the error
+// reaches Sentry before the process dies.
+func WithPanicLevel(level slog.Level) Option {
+	return func(s *SentryHandler) {
+		s.panicLevel = &level
+	}
+}
+
+// WithPanicHandler replaces the default panic of WithPanicLevel with fn.
+// fn receives the context passed to Handle and the SlogError built from the
+// record. When fn returns, Handle returns normally.
+func WithPanicHandler(fn func(ctx context.Context, err error)) Option {
+	return func(s *SentryHandler) {
+		s.panicHandler = fn
+	}
+}
+
+// WithAttachStacktrace attaches the current stack trace to message events,
+// like Sentry's AttachStacktrace client option. Frames inside the handler
+// are left out.
+func WithAttachStacktrace(attach bool) Option {
+	return func(s *SentryHandler) {
+		s.attachStacktrace = attach
+	}
+}
+
+// WithWarnAsException captures Warn records carrying an error as exceptions
+// at the warning level, instead of as messages.
+func WithWarnAsException(enable bool) Option {
+	return func(s *SentryHandler) {
+		s.warnAsException = enable
+	}
+}
+
+// WithTagPrefix sets Sentry tags from the attributes whose key starts with
+// prefix, using the key without the prefix as the tag name. An empty prefix,
+// the default, sets no tags.
+func WithTagPrefix(prefix string) Option {
+	return WithTagPrefixes(prefix)
+}
+
+// WithTagPrefixes is like WithTagPrefix for several prefixes, e.g. "t_" and
+// "tag_". The first prefix a key starts with is removed from the tag name.
+func WithTagPrefixes(prefixes ...string) Option {
+	return func(s *SentryHandler) {
+		s.tagPrefixes = slices.Clone(prefixes)
+	}
+}
+
+// WithMaxTags limits the number of tags set on an event to max, 50 by
+// default. The tags kept are the first in key order; the others are moved to
+// the "_tags_overflow" key of the slog context. A max of 0 or less disables
+// the limit.
+func WithMaxTags(max int) Option {
+	return func(s *SentryHandler) {
+		s.maxTags = max
+	}
+}
+
+// WithBeforeCapture calls fn with each event before it is sent to Sentry,
+// and the context of its record. fn may modify the event, or return nil to
+// drop it.
+func WithBeforeCapture(fn func(ctx context.Context, event *sentry.Event, record slog.Record) *sentry.Event) Option {
+	return func(s *SentryHandler) {
+		s.beforeCapture = fn
+	}
+}
+
+// WithOnCapture calls fn after each attempt to send a record to Sentry, and
+// after each record added as a breadcrumb, with the context of the record
+// and how it was captured. The eventID is nil for breadcrumbs, and when the
+// event was dropped, either by WithBeforeCapture or by the Sentry client,
+// e.g. by its BeforeSend or SampleRate options. Skipped records are not
+// reported.
+func WithOnCapture(fn func(ctx context.Context, record slog.Record, mode CaptureMode, eventID *sentry.EventID)) Option {
+	return func(s *SentryHandler) {
+		s.onCapture = fn
+	}
+}
+
+// WithContextProvider adds the map returned by fn for the context of each
+// captured record to the slog context of its event. Attributes with the same
+// key take precedence. fn may return nil to add nothing.
+func WithContextProvider(fn func(ctx context.Context) map[string]any) Option {
+	return func(s *SentryHandler) {
+		s.contextProvider = fn
+	}
+}
+
+// WithMessageOnlyWhenNoError captures Error records without an error as
+// messages at the error level, instead of as exceptions typed by the message
+// prefix before the first ": ".
+func WithMessageOnlyWhenNoError(enable bool) Option {
+	return func(s *SentryHandler) {
+		s.messageOnlyWhenNoError = enable
+	}
+}
+
+// WithMechanism sets the mechanism of captured exceptions, which tells Sentry
+// how the error surfaced. The default is type "slog", handled.
+func WithMechanism(mechanismType string, handled bool) Option {
+	return func(s *SentryHandler) {
+		s.mechanismType = mechanismType
+		s.mechanismHandled = handled
+	}
+}
+
+// WithLevelHub captures records at level with hub, e.g. to send them to
+// another Sentry project, instead of with the hub in the context of the
+// record or the current hub.
+func WithLevelHub(level slog.Level, hub *sentry.Hub) Option {
+	return func(s *SentryHandler) {
+		if s.levelHubs == nil {
+			s.levelHubs = map[slog.Level]*sentry.Hub{}
+		}
+		s.levelHubs[level] = hub
+	}
+}
+
+// WithRateLimit captures at most max records per window of time. The records
+// over the limit are still handled by the wrapped handler. Records at the
+// panic level are always captured.
+func WithRateLimit(max int, window time.Duration) Option {
+	return func(s *SentryHandler) {
+		s.rateLimiter = &rateLimiter{max: max, window: window}
+	}
+}
+
+// WithClock sets the clock used for the timestamp of records without a time,
+// rate limit windows and dedup windows, time.Now by default.
+func WithClock(now func() time.Time) Option {
+	return func(s *SentryHandler) {
+		s.now = now
+	}
+}
+
+// WithGroupPathTag sets the tag name, e.g. "log_group", holding the path of
+// the groups added with WithGroup, joined by dots like "http.handler.auth".
+// An empty name, the default, sets no tag.
+func WithGroupPathTag(name string) Option {
+	return func(s *SentryHandler) {
+		s.groupPathTag = name
+	}
+}
+
+// WithMaxAttrs adds at most max attributes to the context of an event, and
+// the number of attributes dropped to its "_attrs_dropped" key. Tag and error
+// attributes are always handled. A max of 0 or less, the default, disables
+// the limit.
+func WithMaxAttrs(max int) Option {
+	return func(s *SentryHandler) {
+		s.maxAttrs = max
+	}
+}
+
+// WithUseExtra adds the attributes to the extra data of events, instead of
+// to their slog context.
+func WithUseExtra(enable bool) Option {
+	return func(s *SentryHandler) {
+		s.useExtra = enable
+	}
+}
+
+// WithSourceLocation adds the function, file and line of the logging call
+// to the "source" key of the context, and sets the transaction of message
+// events to the function, so that Sentry groups them by call site.
+func WithSourceLocation(enable bool) Option {
+	return func(s *SentryHandler) {
+		s.sourceLocation = enable
+	}
+}
+
+// WithReplaceAttr applies fn to the attributes before they are captured, like
+// slog.HandlerOptions.ReplaceAttr. Pass the ReplaceAttr of the wrapped
+// handler, e.g. one redacting secrets, so that Sentry gets the same values
+// as the local output. Attributes replaced by one with an empty key are
+// discarded.
+func WithReplaceAttr(fn func(groups []string, attr slog.Attr) slog.Attr) Option {
+	return func(s *SentryHandler) {
+		s.replaceAttr = fn
+	}
+}
+
+// WithName sets the "logger_name" tag of events to name, to tell apart the
+// loggers of an application, e.g. "http" and "worker". Unlike the Logger
+// field of Sentry events, the tag is searchable.
+func WithName(name string) Option {
+	return func(s *SentryHandler) {
+		s.name = name
+	}
+}
+
+// WithMaxValueLength truncates the strings in the context and tags of events
+// to max bytes, 8192 by default, so that a single huge attribute cannot
+// produce a huge event. A max of 0 or less disables the limit.
+func WithMaxValueLength(max int) Option {
+	return func(s *SentryHandler) {
+		s.maxValueLength = max
+	}
+}
+
+// WithTagFunc sets the tags returned by fn for each captured record, for tags
+// computed from its context or the record itself. Tag attributes with the
+// same name take precedence.
+func WithTagFunc(fn func(ctx context.Context, record slog.Record) map[string]string) Option {
+	return func(s *SentryHandler) {
+		s.tagFunc = fn
+	}
+}
+
+// WithReportInnerErrors adds the errors returned by the wrapped handler, e.g.
+// when the disk is full, as breadcrumbs to the hub, so that the next event
+// shows them. Handle still returns the error.
+func WithReportInnerErrors(enable bool) Option {
+	return func(s *SentryHandler) {
+		s.reportInnerErrors = enable
+	}
+}
+
+// WithErrorTypeTag sets the tag name, e.g. "error_type", holding the type of
+// the deepest error wrapped by the error of exceptions, like "*net.OpError".
+// An empty name, the default, sets no tag.
+func WithErrorTypeTag(name string) Option {
+	return func(s *SentryHandler) {
+		s.errorTypeTag = name
+	}
+}
+
+// WithEnvironment sets the environment of events, e.g. "production",
+// instead of the Environment of the Sentry client options.
+func WithEnvironment(environment string) Option {
+	return func(s *SentryHandler) {
+		s.environment = environment
+	}
+}
+
+// WithErrorDedup captures a record with the same root error, the deepest
+// error it wraps, at most once per window of time. So a retried operation
+// logging the same cause, wrapped differently, does not flood Sentry.
+func WithErrorDedup(window time.Duration) Option {
+	return func(s *SentryHandler) {
+		s.errorDeduper = &errorDeduper{window: window, seen: map[string]time.Time{}}
+	}
+}
+
+// WithAllLevels captures records at every level the wrapped handler is
+// enabled for, whatever the levels passed to NewSentryHandler. Records at
+// levels between slog's own are captured like the nearest level below them.
+func WithAllLevels() Option {
+	return func(s *SentryHandler) {
+		s.allLevels = true
+	}
+}
+
+// WithUserPrefix sets the user of events from the attributes whose key starts
+// with prefix, e.g. "user_": the keys without the prefix "id", "email",
+// "ip_address", "username", "name" and "segment" set those fields, others are
+// added to the user data. An empty prefix, the default, sets no user.
+func WithUserPrefix(prefix string) Option {
+	return func(s *SentryHandler) {
+		s.userPrefix = prefix
+	}
+}
+
+// WithUserFromContext sets the user of events to the user fn returns for the
+// context of the record, e.g. the authenticated user. User attributes, see
+// WithUserPrefix, override its fields. fn may return nil for no user.
+func WithUserFromContext(fn func(ctx context.Context) *sentry.User) Option {
+	return func(s *SentryHandler) {
+		s.userFromContext = fn
+	}
+}
+
+// WithLevelTag sets the "log_level" tag of events to the name of the slog
+// level of the record, e.g. "INFO" or "ERROR+4", so events can be filtered by
+// the slog level rather than by the Sentry level it maps to.
+func WithLevelTag(enable bool) Option {
+	return func(s *SentryHandler) {
+		s.levelTag = enable
+	}
+}
+
+// WithErrorExtras duplicates the attributes of exception events into the
+// Sentry extra data, which the issue page shows prominently. Message events
+// keep the attributes in the context only.
+func WithErrorExtras(enable bool) Option {
+	return func(s *SentryHandler) {
+		s.errorExtras = enable
+	}
+}
+
+// WithEventModifier calls fn with every event built, and the context of its
+// record, to set fields no other option covers. Unlike the hook of
+// WithBeforeCapture, fn cannot drop the event. It also applies to
+// CaptureToEvent.
+func WithEventModifier(fn func(ctx context.Context, event *sentry.Event)) Option {
+	return func(s *SentryHandler) {
+		s.eventModifier = fn
+	}
+}
+
+// WithEventProcessor runs processor on the events of the handler, in a clone
+// of the hub, after the event processors of the scope of the hub. Passing
+// the option several times chains the processors in order. A processor
+// returning nil drops the event.
+func WithEventProcessor(processor sentry.EventProcessor) Option {
+	return func(s *SentryHandler) {
+		s.eventProcessors = append(slices.Clip(s.eventProcessors), processor)
+	}
+}
+
+// WithWarnEscalation adds warnings as breadcrumbs, until threshold warnings
+// with the same message occur within window of the first: from then on they
+// are captured as events. So a one-off warning gives context to a later
+// event, while a persistent one is surfaced itself.
+func WithWarnEscalation(threshold int, window time.Duration) Option {
+	return func(s *SentryHandler) {
+		s.warnEscalator = &warnEscalator{threshold: threshold, window: window, counts: map[string]*warnCount{}}
+	}
+}
+
+// WithFlushTimeout sets the timeout of flushing the hub before panicking,
+// see WithPanicLevel, and after capturing records at the levels of
+// WithFlushLevels. The default is 2 seconds.
+func WithFlushTimeout(timeout time.Duration) Option {
+	return func(s *SentryHandler) {
+		s.flushTimeout = timeout
+	}
+}
+
+// WithFingerprintSplit sets the separator of the parts of a fingerprint
+// attribute with a string value, e.g. " " for "agi timeout db". The default
+// is ",". An empty separator makes the whole string a single part.
+func WithFingerprintSplit(sep string) Option {
+	return func(s *SentryHandler) {
+		s.fingerprintSep = sep
+	}
+}
+
+// WithSendAsLogs sends the records at levels to Sentry as logs rather than
+// as issues. The sentry-go version this module uses has no logs API yet, so
+// they are captured as message events, never as exceptions, at the Sentry
+// level matching the slog level, e.g. sentry.LevelDebug for slog.LevelDebug.
+func WithSendAsLogs(levels ...slog.Level) Option {
+	return func(s *SentryHandler) {
+		s.sendAsLogs = slices.Clone(levels)
+	}
+}
+
+// WithIgnoreErrors does not capture records with an error matching one of
+// errs, using errors.Is; they are still handled by the wrapped handler.
+// Without errs, it ignores context.Canceled and context.DeadlineExceeded,
+// which are rarely worth an issue.
+func WithIgnoreErrors(errs ...error) Option {
+	if len(errs) == 0 {
+		errs = []error{context.Canceled, context.DeadlineExceeded}
+	}
+	return func(s *SentryHandler) {
+		s.ignoreErrors = append(slices.Clip(s.ignoreErrors), errs...)
+	}
+}
+
+// WithIgnoreErrorTypes does not capture records with an error of the type of
+// one of errs, using errors.As, e.g. (*net.OpError)(nil) ignores network
+// errors. They are still handled by the wrapped handler. It panics when a
+// type does not implement error.
+func WithIgnoreErrorTypes(errs ...any) Option {
+	errorType := reflect.TypeOf((*error)(nil)).Elem()
+	types := make([]reflect.Type, 0, len(errs))
+	for _, err := range errs {
+		typ := reflect.TypeOf(err)
+		if typ == nil || !typ.Implements(errorType) {
+			panic(fmt.Sprintf("slogsentry: WithIgnoreErrorTypes: %T does not implement error", err))
+		}
+		types = append(types, typ)
+	}
+	return func(s *SentryHandler) {
+		s.ignoreErrorTypes = append(slices.Clip(s.ignoreErrorTypes), types...)
+	}
+}
+
+// WithDist sets the distribution of events, e.g. the build number, which
+// tells builds of the same release apart. A "dist" attribute overrides it.
+func WithDist(dist string) Option {
+	return func(s *SentryHandler) {
+		s.dist = dist
+	}
+}
+
+// WithGroupTagNamespace adds the path of the groups of a tag attribute to
+// the tag name, e.g. the attribute "tag_method" in WithGroup("http") sets the
+// tag "http.method" rather than "method", and so does Tag("method", ...). The
+// path is the groups the attribute was added in, so an attribute added with
+// WithAttrs before WithGroup is not affected by that group.
+func WithGroupTagNamespace(enable bool) Option {
+	return func(s *SentryHandler) {
+		s.groupTagNamespace = enable
+	}
+}
+
+// WithErrorChainBreadcrumbs adds a breadcrumb to exception events for every
+// error in the chain of the error of the record, from the deepest cause to
+// the error logged, so the event shows the message of every wrap.
+func WithErrorChainBreadcrumbs(enable bool) Option {
+	return func(s *SentryHandler) {
+		s.errorChainBreadcrumbs = enable
+	}
+}
+
+// WithGroupAsContextSection adds the attributes in a group to a Sentry
+// context named after the top-level group, rather than to the slog context,
+// e.g. the attributes in WithGroup("db") to the db context, and those of a
+// top-level slog.Group("cache", ...) attribute to the cache context. A group
+// should not be named after a context set by Sentry, such as "os" or "trace".
+func WithGroupAsContextSection(enable bool) Option {
+	return func(s *SentryHandler) {
+		s.groupAsContextSection = enable
+	}
+}
+
+// WithGroupInMessage prefixes the message of message events with the path of
+// the groups of the handler, e.g. "[http.auth] login failed", so the issue
+// titles tell where in the application they come from.
+func WithGroupInMessage(enable bool) Option {
+	return func(s *SentryHandler) {
+		s.groupInMessage = enable
+	}
+}
+
+// WithJSONBytes adds []byte attributes holding valid JSON to the context as
+// the structure they encode, like json.RawMessage attributes always are.
+// Other []byte attributes are added as strings.
+func WithJSONBytes(enable bool) Option {
+	return func(s *SentryHandler) {
+		s.jsonBytes = enable
+	}
+}
+
+// WithExtraKeys adds the attributes with one of keys to the Sentry extra
+// data rather than to the context, like WithUseExtra does for all of them.
+func WithExtraKeys(keys ...string) Option {
+	return func(s *SentryHandler) {
+		s.extraKeys = append(slices.Clip(s.extraKeys), keys...)
+	}
+}
+
+// WithKeepDefaultKeys adds the attributes with one of slog's built-in keys
+// "time", "level", "source" and "msg" to the context, e.g. a "source"
+// attribute added by the caller, rather than ignoring them. The error keys
+// "err" and "error" always set the error of the record.
+func WithKeepDefaultKeys(keys ...string) Option {
+	return func(s *SentryHandler) {
+		s.keepDefaultKeys = append(slices.Clip(s.keepDefaultKeys), keys...)
+	}
+}
+
+// WithMessageAsFingerprint groups events by the message of the record, also
+// when it has an error, for messages that are stable templates with the
+// dynamic parts in attributes. A fingerprint attribute overrides it.
+func WithMessageAsFingerprint(enable bool) Option {
+	return func(s *SentryHandler) {
+		s.messageAsFingerprint = enable
+	}
+}
+
+// WithMessageFormatter sets the message of events, and of the SlogError of
+// exceptions and panics, to what fn returns for the context, the record and
+// its error, e.g. to strip or localize parts of it. The default is the
+// message of the record. Fingerprints and warning escalation still use the
+// record message.
+func WithMessageFormatter(fn func(ctx context.Context, record slog.Record, err error) string) Option {
+	return func(s *SentryHandler) {
+		s.messageFormatter = fn
+	}
+}
+
+// WithTagGroup sets a Sentry tag for every attribute of the group attribute
+// named name, e.g. slog.Group("tags", "region", "eu", "tier", "gold") for
+// WithTagGroup("tags"), whatever the tag prefixes. An empty name, the
+// default, sets no tags from groups.
+func WithTagGroup(name string) Option {
+	return func(s *SentryHandler) {
+		s.tagGroup = name
+	}
+}
+
+// WithStacktraceMinLevel attaches the current stack trace to message events
+// of records at or above level only, like WithAttachStacktrace does for all
+// of them. It overrides WithAttachStacktrace and the AttachStacktrace client
+// option. Exceptions always have a stack trace.
+func WithStacktraceMinLevel(level slog.Level) Option {
+	return func(s *SentryHandler) {
+		s.stacktraceMinLevel = &level
+	}
+}
+
+// WithDisableContext never builds the slog context of events, e.g. to keep
+// personal data out of Sentry, so that only tags, the user, the request and
+// errors of the attributes are captured. Sections set by reserved keys, like
+// validation_errors, are still added.
+func WithDisableContext(disable bool) Option {
+	return func(s *SentryHandler) {
+		s.disableContext = disable
+	}
+}
+
+// WithFilter captures only the records for which fn returns true, before
+// building their event; the others are still handled by the wrapped handler.
+// Filtered out records that panic still panic, without being captured.
+func WithFilter(fn func(ctx context.Context, record slog.Record) bool) Option {
+	return func(s *SentryHandler) {
+		s.filter = fn
+	}
+}
+
+// WithLevelNameMapping sets the Sentry level of records by the name of their
+// level, e.g. "NOTICE" for a custom level named with WithReplaceAttr, like
+// the wrapped handler names it. The name is the String form of the level
+// without WithReplaceAttr, e.g. "INFO+2". Records mapped to the error or
+// fatal level with an error are captured as exceptions, the others as
+// messages. Which records are captured is still set by the levels.
+func WithLevelNameMapping(mapping map[string]sentry.Level) Option {
+	return func(s *SentryHandler) {
+		s.levelNames = maps.Clone(mapping)
+	}
+}
+
+// WithStringErrors captures a string value of an error key, e.g.
+// slog.String("error", "something failed"), as an error, like an error
+// value, instead of adding it to the context.
+func WithStringErrors(enable bool) Option {
+	return func(s *SentryHandler) {
+		s.stringErrors = enable
+	}
+}
+
+// WithAlwaysException captures all records as exceptions, at the level they
+// would be captured as messages at, so that they all have a stack trace and
+// are grouped by it. The value of the exception is the message, and its type
+// the prefix of the message before ": ", if any, else the SlogError type.
+// Records sent as logs with WithSendAsLogs stay messages.
+func WithAlwaysException(enable bool) Option {
+	return func(s *SentryHandler) {
+		s.alwaysException = enable
+	}
+}
+
+// WithMaxContextSize trims every context section of events, or their extra
+// data with WithUseExtra, to max bytes of JSON, as Sentry drops oversized
+// data silently. The entries with the last keys in sorted order are dropped
+// first, and their number is added under the "_trimmed" key. A max of 0 or
+// less, the default, disables the limit.
+func WithMaxContextSize(max int) Option {
+	return func(s *SentryHandler) {
+		s.maxContextSize = max
+	}
+}
+
+// WithMaxBreadcrumbDataSize trims the data of the breadcrumbs of records,
+// warnings below the threshold of WithWarnEscalation and records captured
+// with ModeBreadcrumb by WithCaptureStrategy, to max bytes of JSON, like
+// WithMaxContextSize does for contexts. A max of 0 or less, the default,
+// disables the limit.
+func WithMaxBreadcrumbDataSize(max int) Option {
+	return func(s *SentryHandler) {
+		s.maxBreadcrumbDataSize = max
+	}
+}
+
+// WithRouting captures records with the hub fn returns for the context and
+// record passed to Handle and a copy of the tags of its event, e.g. to send
+// the events of each team to its own Sentry project. When fn returns nil,
+// records are captured with the hub of WithLevelHub, the context or the
+// current hub, as without routing.
+func WithRouting(fn func(ctx context.Context, record slog.Record, tags map[string]string) *sentry.Hub) Option {
+	return func(s *SentryHandler) {
+		s.routing = fn
+	}
+}
+
+// WithCaptureStrategy captures records the way fn returns for the record and
+// the mode it would be captured with otherwise, e.g. ModeBreadcrumb to keep
+// noisy messages out of Sentry issues, or ModeSkip to drop them. Records
+// skipped with sentry_skip, or with an error ignored by WithIgnoreErrors or
+// WithIgnoreErrorTypes, are never captured, and records that panic always
+// are, without calling fn.
+func WithCaptureStrategy(fn func(ctx context.Context, record slog.Record, mode CaptureMode) CaptureMode) Option {
+	return func(s *SentryHandler) {
+		s.captureStrategy = fn
+	}
+}
+
+// WithTagBucket sets a Sentry tag for the numeric attribute key, with the
+// bucket of its value between the boundaries as value, rather than adding
+// it to the context, to keep the number of tag values low. For boundaries
+// 100 and 500, the buckets are "<100", "100-500" and ">=500"; durations are
+// bucketed in milliseconds. Without boundaries, the attribute is not
+// bucketed.
+func WithTagBucket(key string, boundaries []float64) Option {
+	return func(s *SentryHandler) {
+		if len(boundaries) == 0 {
+			delete(s.tagBuckets, key)
+			return
+		}
+		if s.tagBuckets == nil {
+			s.tagBuckets = map[string][]float64{}
+		}
+		s.tagBuckets[key] = slices.Clone(boundaries)
+		slices.Sort(s.tagBuckets[key])
+	}
+}
+
+// WithMaxDepth replaces the maps and slices in the context nested deeper
+// than depth with "<max depth>", 5 by default, which also guards against
+// cycles. A depth of 0 or less replaces all of them.
+func WithMaxDepth(depth int) Option {
+	return func(s *SentryHandler) {
+		s.maxDepth = depth
+	}
+}
+
+// WithFlushLevels flushes the hub after capturing a record at one of levels,
+// waiting up to the flush timeout for its delivery, e.g. to make sure errors
+// reach Sentry while other records are sent asynchronously.
+func WithFlushLevels(levels ...slog.Level) Option {
+	return func(s *SentryHandler) {
+		s.flushLevels = slices.Clone(levels)
+	}
+}
+
+// WithModifyScope calls fn with the scope of a clone of the hub, the context
+// and the record before sending each event, so that it can set anything the
+// scope supports, e.g. a fingerprint, a level or a user. Sentry applies the
+// scope to the event like any other: tags, extra data and the level of the
+// scope override those of the event, while its fingerprint, user, request
+// and contexts only fill in what the event lacks.
+func WithModifyScope(fn func(scope *sentry.Scope, ctx context.Context, record slog.Record)) Option {
+	return func(s *SentryHandler) {
+		s.modifyScope = fn
+	}
+}
@@ -0,0 +1,2232 @@
+package slogsentry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+func TestWithPanicLevelFlushesBeforePanic(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	handler := newTestHandler(nil, WithPanicLevel(slog.LevelError))
+
+	record := slog.NewRecord(time.Now(), slog.LevelError, "fatal", 0)
+	record.AddAttrs(slog.Any("err", errors.New("the error")))
+
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatal("expect Handle to panic")
+		}
+		if err, ok := r.(error); !ok || err.Error() != "fatal: the error" {
+			t.Errorf("expect panic with %q, got: %v", "fatal: the error", r)
+		}
+		if n := len(transport.Events()); n != 1 {
+			t.Errorf("expect 1 event before panic, got: %d", n)
+		}
+		if n := transport.Flushes(); n != 1 {
+			t.Errorf("expect 1 flush before panic, got: %d", n)
+		}
+	}()
+	_ = handler.Handle(ctx, record)
+}
+
+func TestWithPanicHandler(t *testing.T) {
+	type requestKey struct{}
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	ctx = context.WithValue(ctx, requestKey{}, "req-1")
+	var panicErr error
+	handler := newTestHandler(nil,
+		WithPanicLevel(slog.LevelError),
+		WithPanicHandler(func(ctx context.Context, err error) {
+			if transport.Flushes() != 1 {
+				t.Error("expect flush before panic handler")
+			}
+			if id := ctx.Value(requestKey{}); id != "req-1" {
+				t.Errorf("expect the context of Handle with request %q, got: %v", "req-1", id)
+			}
+			panicErr = err
+		}),
+	)
+
+	if err := handler.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelWarn, "warn", 0)); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+	if panicErr != nil {
+		t.Errorf("expect no panic below the panic level, got: %s", panicErr)
+	}
+
+	if err := handler.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelError+4, "fatal", 0)); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+	if panicErr == nil || panicErr.Error() != "fatal" {
+		t.Errorf("expect panic handler called with %q, got: %v", "fatal", panicErr)
+	}
+	if n := len(transport.Events()); n != 1 {
+		t.Errorf("expect 1 event, got: %d", n)
+	}
+}
+
+func TestWithAttachStacktrace(t *testing.T) {
+	for _, attach := range []bool{false, true} {
+		ctx, transport := newTestContext(t, sentry.ClientOptions{})
+		handler := newTestHandler([]slog.Level{slog.LevelInfo}, WithAttachStacktrace(attach))
+
+		if err := handler.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelInfo, "info", 0)); err != nil {
+			t.Fatalf("error from Handle: %s", err)
+		}
+
+		events := transport.Events()
+		if len(events) != 1 {
+			t.Fatalf("expect 1 event, got: %d", len(events))
+		}
+		if !attach {
+			if len(events[0].Threads) != 0 {
+				t.Errorf("expect no threads, got: %d", len(events[0].Threads))
+			}
+			continue
+		}
+		if len(events[0].Threads) != 1 || events[0].Threads[0].Stacktrace == nil {
+			t.Fatalf("expect a thread with a stacktrace, got: %+v", events[0].Threads)
+		}
+		frames := events[0].Threads[0].Stacktrace.Frames
+		if len(frames) == 0 {
+			t.Fatal("expect stacktrace frames")
+		}
+		if top := frames[len(frames)-1]; top.Function != "TestWithAttachStacktrace" {
+			t.Errorf("expect top frame %q, got: %q", "TestWithAttachStacktrace", top.Function)
+		}
+	}
+}
+
+func TestWithWarnAsException(t *testing.T) {
+	tests := []struct {
+		enable          bool
+		err             error
+		expectException bool
+	}{
+		{false, errors.New("the error"), false},
+		{true, errors.New("the error"), true},
+		{true, nil, false},
+	}
+
+	for i, test := range tests {
+		ctx, transport := newTestContext(t, sentry.ClientOptions{})
+		handler := newTestHandler([]slog.Level{slog.LevelWarn}, WithWarnAsException(test.enable))
+
+		record := slog.NewRecord(time.Now(), slog.LevelWarn, "the message", 0)
+		if test.err != nil {
+			record.AddAttrs(slog.Any("err", test.err))
+		}
+		if err := handler.Handle(ctx, record); err != nil {
+			t.Fatalf("test %d: error from Handle: %s", i, err)
+		}
+
+		events := transport.Events()
+		if len(events) != 1 {
+			t.Fatalf("test %d: expect 1 event, got: %d", i, len(events))
+		}
+		if isException := len(events[0].Exception) > 0; isException != test.expectException {
+			t.Errorf("test %d: expect exception: %t, got: %t", i, test.expectException, isException)
+		}
+		if test.expectException && events[0].Level != sentry.LevelWarning {
+			t.Errorf("test %d: expect level %q, got: %q", i, sentry.LevelWarning, events[0].Level)
+		}
+	}
+}
+
+func TestWithTagPrefix(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	handler := newTestHandler([]slog.Level{slog.LevelError}, WithTagPrefix("tag_"))
+
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	record.AddAttrs(slog.String("tag_region", "eu"), slog.String("other", "value"))
+	if err := handler.Handle(ctx, record); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("expect 1 event, got: %d", len(events))
+	}
+	if tag := events[0].Tags["region"]; tag != "eu" {
+		t.Errorf("expect tag region %q, got: %q", "eu", tag)
+	}
+	if _, ok := events[0].Contexts["slog"]["tag_region"]; ok {
+		t.Error("expect tag not in context")
+	}
+	if value := events[0].Contexts["slog"]["other"]; value != "value" {
+		t.Errorf("expect context other %q, got: %v", "value", value)
+	}
+}
+
+func TestWithMaxTags(t *testing.T) {
+	tests := []struct {
+		opts          []Option
+		expectTags    int
+		expectFirst   string
+		expectDropped int
+	}{
+		{nil, defaultMaxTags, "t00", 10},
+		{[]Option{WithMaxTags(5)}, 5, "t00", 55},
+		{[]Option{WithMaxTags(0)}, 60, "t00", 0},
+	}
+
+	for i, test := range tests {
+		ctx, transport := newTestContext(t, sentry.ClientOptions{})
+		handler := newTestHandler([]slog.Level{slog.LevelError}, append(test.opts, WithTagPrefix("tag_"))...)
+
+		record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+		for n := 0; n < 60; n++ {
+			record.AddAttrs(slog.Int(fmt.Sprintf("tag_t%02d", n), n))
+		}
+		if err := handler.Handle(ctx, record); err != nil {
+			t.Fatalf("test %d: error from Handle: %s", i, err)
+		}
+
+		events := transport.Events()
+		if len(events) != 1 {
+			t.Fatalf("test %d: expect 1 event, got: %d", i, len(events))
+		}
+		if n := len(events[0].Tags); n != test.expectTags {
+			t.Errorf("test %d: expect %d tags, got: %d", i, test.expectTags, n)
+		}
+		if _, ok := events[0].Tags[test.expectFirst]; !ok {
+			t.Errorf("test %d: expect tag %q to be kept", i, test.expectFirst)
+		}
+		overflow, _ := events[0].Contexts["slog"]["_tags_overflow"].(map[string]string)
+		if len(overflow) != test.expectDropped {
+			t.Errorf("test %d: expect %d overflow tags, got: %d", i, test.expectDropped, len(overflow))
+		}
+	}
+}
+
+func TestWithBeforeCapture(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	handler := newTestHandler([]slog.Level{slog.LevelInfo}, WithBeforeCapture(func(_ context.Context, event *sentry.Event, record slog.Record) *sentry.Event {
+		if record.Message == "drop" {
+			return nil
+		}
+		event.Tags["hooked"] = "yes"
+		return event
+	}))
+
+	for _, msg := range []string{"keep", "drop"} {
+		if err := handler.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelInfo, msg, 0)); err != nil {
+			t.Fatalf("error from Handle: %s", err)
+		}
+	}
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("expect 1 event, got: %d", len(events))
+	}
+	if events[0].Message != "keep" || events[0].Tags["hooked"] != "yes" {
+		t.Errorf("expect modified event %q, got: %q with tags %v", "keep", events[0].Message, events[0].Tags)
+	}
+}
+
+func TestWithOnCaptureSeesDrops(t *testing.T) {
+	tests := []struct {
+		clientDrops bool
+		hookDrops   bool
+		expectID    bool
+	}{
+		{false, false, true},
+		{true, false, false},
+		{false, true, false},
+		{true, true, false},
+	}
+
+	for i, test := range tests {
+		ctx, transport := newTestContext(t, sentry.ClientOptions{
+			BeforeSend: func(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+				if test.clientDrops {
+					return nil
+				}
+				return event
+			},
+		})
+
+		var calls int
+		var eventID *sentry.EventID
+		handler := newTestHandler([]slog.Level{slog.LevelError},
+			WithBeforeCapture(func(_ context.Context, event *sentry.Event, _ slog.Record) *sentry.Event {
+				if test.hookDrops {
+					return nil
+				}
+				return event
+			}),
+			WithOnCapture(func(_ context.Context, _ slog.Record, _ CaptureMode, id *sentry.EventID) {
+				calls++
+				eventID = id
+			}),
+		)
+
+		if err := handler.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)); err != nil {
+			t.Fatalf("test %d: error from Handle: %s", i, err)
+		}
+
+		if calls != 1 {
+			t.Fatalf("test %d: expect 1 on capture call, got: %d", i, calls)
+		}
+		if (eventID != nil) != test.expectID {
+			t.Errorf("test %d: expect event ID: %t, got: %v", i, test.expectID, eventID)
+		}
+		if n := len(transport.Events()); (n == 1) != test.expectID {
+			t.Errorf("test %d: expect sent: %t, got %d events", i, test.expectID, n)
+		}
+	}
+}
+
+type tenantKey struct{}
+
+func TestWithContextProvider(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	handler := newTestHandler([]slog.Level{slog.LevelError}, WithContextProvider(func(ctx context.Context) map[string]any {
+		tenant, ok := ctx.Value(tenantKey{}).(string)
+		if !ok {
+			return nil
+		}
+		return map[string]any{"tenant": tenant}
+	}))
+
+	if err := handler.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelError, "no tenant", 0)); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+	tenantCtx := context.WithValue(ctx, tenantKey{}, "acme")
+	if err := handler.Handle(tenantCtx, slog.NewRecord(time.Now(), slog.LevelError, "tenant", 0)); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+
+	events := transport.Events()
+	if len(events) != 2 {
+		t.Fatalf("expect 2 events, got: %d", len(events))
+	}
+	if _, ok := events[0].Contexts["slog"]; ok {
+		t.Error("expect no slog context without tenant")
+	}
+	if tenant := events[1].Contexts["slog"]["tenant"]; tenant != "acme" {
+		t.Errorf("expect tenant %q, got: %v", "acme", tenant)
+	}
+}
+
+func TestWithMessageOnlyWhenNoError(t *testing.T) {
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	event := CaptureToEvent(context.Background(), record, WithMessageOnlyWhenNoError(true))
+	if event == nil {
+		t.Fatal("expect an event")
+	}
+	if event.Message != "the message" || len(event.Exception) != 0 {
+		t.Errorf("expect message event %q, got: %q with %d exceptions", "the message", event.Message, len(event.Exception))
+	}
+	if event.Level != sentry.LevelError {
+		t.Errorf("expect level %q, got: %q", sentry.LevelError, event.Level)
+	}
+
+	record.AddAttrs(slog.Any("err", errors.New("the error")))
+	event = CaptureToEvent(context.Background(), record, WithMessageOnlyWhenNoError(true))
+	if event == nil || len(event.Exception) == 0 {
+		t.Errorf("expect an exception event with an error, got: %+v", event)
+	}
+}
+
+func TestWithMechanism(t *testing.T) {
+	tests := []struct {
+		opts          []Option
+		expectType    string
+		expectHandled bool
+	}{
+		{nil, "slog", true},
+		{[]Option{WithMechanism("worker", false)}, "worker", false},
+	}
+
+	for i, test := range tests {
+		record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+		record.AddAttrs(slog.Any("err", errors.New("the error")))
+		event := CaptureToEvent(context.Background(), record, test.opts...)
+		if event == nil || len(event.Exception) == 0 {
+			t.Fatalf("test %d: expect an exception event, got: %+v", i, event)
+		}
+
+		mechanism := event.Exception[len(event.Exception)-1].Mechanism
+		if mechanism == nil || mechanism.Handled == nil {
+			t.Fatalf("test %d: expect a mechanism, got: %+v", i, mechanism)
+		}
+		if mechanism.Type != test.expectType || *mechanism.Handled != test.expectHandled {
+			t.Errorf("test %d: expect type %q handled %t, got: type %q handled %t", i, test.expectType, test.expectHandled, mechanism.Type, *mechanism.Handled)
+		}
+	}
+}
+
+func TestWithLevelHub(t *testing.T) {
+	ctx, defaultTransport := newTestContext(t, sentry.ClientOptions{})
+	criticalHub, criticalTransport := newTestHub(t, sentry.ClientOptions{})
+	noiseHub, noiseTransport := newTestHub(t, sentry.ClientOptions{})
+	handler := newTestHandler([]slog.Level{slog.LevelInfo, slog.LevelWarn, slog.LevelError},
+		WithLevelHub(slog.LevelError, criticalHub),
+		WithLevelHub(slog.LevelWarn, noiseHub),
+	)
+
+	for _, level := range []slog.Level{slog.LevelInfo, slog.LevelWarn, slog.LevelError} {
+		if err := handler.Handle(ctx, slog.NewRecord(time.Now(), level, level.String(), 0)); err != nil {
+			t.Fatalf("error from Handle: %s", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		transport *transportMock
+		expect    string
+	}{
+		{"default", defaultTransport, "INFO"},
+		{"noise", noiseTransport, "WARN"},
+		{"critical", criticalTransport, "ERROR"},
+	}
+	for _, test := range tests {
+		events := test.transport.Events()
+		if len(events) != 1 {
+			t.Errorf("%s hub: expect 1 event, got: %d", test.name, len(events))
+			continue
+		}
+		msg := events[0].Message
+		if len(events[0].Exception) > 0 {
+			msg = events[0].Exception[0].Value
+		}
+		if msg != test.expect {
+			t.Errorf("%s hub: expect %q, got: %q", test.name, test.expect, msg)
+		}
+	}
+}
+
+func TestWithRateLimitAndClock(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	handler := newTestHandler([]slog.Level{slog.LevelError},
+		WithRateLimit(2, time.Minute),
+		WithClock(func() time.Time { return now }),
+	)
+
+	tests := []struct {
+		advance      time.Duration
+		expectEvents int
+	}{
+		{0, 1},
+		{10 * time.Second, 2},
+		{10 * time.Second, 2},
+		{40 * time.Second, 3},
+		{time.Second, 4},
+		{time.Second, 4},
+		{time.Minute, 5},
+	}
+
+	for i, test := range tests {
+		now = now.Add(test.advance)
+		if err := handler.Handle(ctx, slog.NewRecord(now, slog.LevelError, "the message", 0)); err != nil {
+			t.Fatalf("test %d: error from Handle: %s", i, err)
+		}
+		if n := len(transport.Events()); n != test.expectEvents {
+			t.Fatalf("test %d: expect %d events, got: %d", i, test.expectEvents, n)
+		}
+	}
+
+	if err := handler.Handle(ctx, slog.Record{Level: slog.LevelError, Message: "no time"}); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+	events := transport.Events()
+	if timestamp := events[len(events)-1].Timestamp; !timestamp.Equal(now) {
+		t.Errorf("expect timestamp from the clock %s, got: %s", now, timestamp)
+	}
+}
+
+func TestWithGroupPathTag(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	handler := newTestHandler([]slog.Level{slog.LevelError}, WithGroupPathTag("log_group"))
+	grouped := handler.WithGroup("http").WithGroup("handler").WithAttrs([]slog.Attr{slog.Int("n", 1)}).WithGroup("auth")
+
+	for _, h := range []slog.Handler{handler, grouped} {
+		if err := h.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)); err != nil {
+			t.Fatalf("error from Handle: %s", err)
+		}
+	}
+
+	events := transport.Events()
+	if len(events) != 2 {
+		t.Fatalf("expect 2 events, got: %d", len(events))
+	}
+	if tag, ok := events[0].Tags["log_group"]; ok {
+		t.Errorf("expect no tag without groups, got: %q", tag)
+	}
+	if tag := events[1].Tags["log_group"]; tag != "http.handler.auth" {
+		t.Errorf("expect tag %q, got: %q", "http.handler.auth", tag)
+	}
+}
+
+func TestWithMaxAttrs(t *testing.T) {
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	for n := 0; n < 200; n++ {
+		record.AddAttrs(slog.Int(fmt.Sprintf("attr%03d", n), n))
+	}
+	record.AddAttrs(slog.String("tag_region", "eu"), slog.Any("err", errors.New("the error")))
+
+	event := CaptureToEvent(context.Background(), record, WithMaxAttrs(50), WithTagPrefix("tag_"))
+	if event == nil {
+		t.Fatal("expect an event")
+	}
+	slogContext := event.Contexts["slog"]
+	if n := len(slogContext); n != 51 {
+		t.Errorf("expect 50 attributes and the dropped count, got: %d", n)
+	}
+	if _, ok := slogContext["attr049"]; !ok {
+		t.Error("expect the first 50 attributes to be kept")
+	}
+	if dropped := slogContext["_attrs_dropped"]; dropped != 150 {
+		t.Errorf("expect 150 dropped, got: %v", dropped)
+	}
+	if tag := event.Tags["region"]; tag != "eu" {
+		t.Errorf("expect tag region %q, got: %q", "eu", tag)
+	}
+	if value := event.Exception[len(event.Exception)-1].Value; value != "the message: the error" {
+		t.Errorf("expect the error to be handled, got: %q", value)
+	}
+}
+
+func TestWithUseExtra(t *testing.T) {
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	record.AddAttrs(slog.String("some_attr", "yes"))
+
+	event := CaptureToEvent(context.Background(), record, WithUseExtra(true))
+	if event == nil {
+		t.Fatal("expect an event")
+	}
+	if value := event.Extra["some_attr"]; value != "yes" {
+		t.Errorf("expect extra some_attr %q, got: %v", "yes", value)
+	}
+	if _, ok := event.Contexts["slog"]; ok {
+		t.Error("expect no slog context")
+	}
+}
+
+func TestWithSourceLocation(t *testing.T) {
+	var pcs [1]uintptr
+	runtime.Callers(1, pcs[:])
+	function := packagePath + ".TestWithSourceLocation"
+
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, "the message", pcs[0])
+	event := CaptureToEvent(context.Background(), record, WithSourceLocation(true))
+	if event == nil {
+		t.Fatal("expect an event")
+	}
+	if event.Transaction != function {
+		t.Errorf("expect transaction %q, got: %q", function, event.Transaction)
+	}
+	source, _ := event.Contexts["slog"]["source"].(map[string]any)
+	if source["function"] != function || !strings.HasSuffix(source["file"].(string), "options_test.go") {
+		t.Errorf("expect source of %q in options_test.go, got: %v", function, source)
+	}
+
+	if event := CaptureToEvent(context.Background(), record); event.Transaction != "" {
+		t.Errorf("expect no transaction by default, got: %q", event.Transaction)
+	}
+}
+
+func TestWithReplaceAttr(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	var groupsSeen [][]string
+	redact := func(groups []string, attr slog.Attr) slog.Attr {
+		switch attr.Key {
+		case "password":
+			groupsSeen = append(groupsSeen, groups)
+			return slog.String(attr.Key, "REDACTED")
+		case "internal":
+			return slog.Attr{}
+		}
+		return attr
+	}
+
+	var local bytes.Buffer
+	inner := slog.NewJSONHandler(&local, &slog.HandlerOptions{ReplaceAttr: redact})
+	handler := NewSentryHandler(inner, []slog.Level{slog.LevelError}, WithReplaceAttr(redact)).
+		WithGroup("db").
+		WithAttrs([]slog.Attr{slog.String("password", "stored-secret")})
+
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	record.AddAttrs(slog.String("internal", "x"), slog.String("user", "bob"))
+	if err := handler.Handle(ctx, record); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+
+	if strings.Contains(local.String(), "stored-secret") || !strings.Contains(local.String(), "REDACTED") {
+		t.Errorf("expect local output to be redacted, got: %s", local.String())
+	}
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("expect 1 event, got: %d", len(events))
+	}
+	slogContext := events[0].Contexts["slog"]
+	if password := slogContext["password"]; password != "REDACTED" {
+		t.Errorf("expect Sentry password %q, got: %v", "REDACTED", password)
+	}
+	if _, ok := slogContext["internal"]; ok {
+		t.Error("expect discarded attribute not in context")
+	}
+	if user := slogContext["user"]; user != "bob" {
+		t.Errorf("expect user %q, got: %v", "bob", user)
+	}
+	for _, groups := range groupsSeen {
+		if !slices.Equal(groups, []string{"db"}) {
+			t.Errorf("expect groups %q, got: %q", []string{"db"}, groups)
+		}
+	}
+}
+
+func TestWithReplaceAttrInGroup(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	var groupsSeen []string
+	redact := func(groups []string, attr slog.Attr) slog.Attr {
+		if attr.Key == "password" {
+			groupsSeen = groups
+			return slog.String(attr.Key, "REDACTED")
+		}
+		return attr
+	}
+
+	var local bytes.Buffer
+	inner := slog.NewTextHandler(&local, &slog.HandlerOptions{ReplaceAttr: redact})
+	handler := NewSentryHandler(inner, []slog.Level{slog.LevelError}, WithReplaceAttr(redact))
+
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	record.AddAttrs(slog.Group("auth",
+		slog.String("user", "bob"),
+		slog.String("password", "hunter2"),
+		slog.Group("", slog.Int("attempt", 2)),
+	))
+	if err := handler.Handle(ctx, record); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+
+	if !strings.Contains(local.String(), "auth.password=REDACTED") {
+		t.Errorf("expect local output to be redacted, got: %s", local.String())
+	}
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("expect 1 event, got: %d", len(events))
+	}
+	expected := map[string]any{"user": "bob", "password": "REDACTED", "attempt": "2"}
+	if auth := events[0].Contexts["slog"]["auth"]; !reflect.DeepEqual(auth, expected) {
+		t.Errorf("expect auth %v, got: %v", expected, auth)
+	}
+	if !slices.Equal(groupsSeen, []string{"auth"}) {
+		t.Errorf("expect groups %q, got: %q", []string{"auth"}, groupsSeen)
+	}
+}
+
+func TestWithName(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	handler := newTestHandler([]slog.Level{slog.LevelError}, WithName("worker"))
+	derived := handler.WithAttrs([]slog.Attr{slog.Int("n", 1)}).WithGroup("job")
+
+	for _, h := range []slog.Handler{handler, derived} {
+		if err := h.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)); err != nil {
+			t.Fatalf("error from Handle: %s", err)
+		}
+	}
+
+	events := transport.Events()
+	if len(events) != 2 {
+		t.Fatalf("expect 2 events, got: %d", len(events))
+	}
+	for i, event := range events {
+		if tag := event.Tags["logger_name"]; tag != "worker" {
+			t.Errorf("event %d: expect tag logger_name %q, got: %q", i, "worker", tag)
+		}
+	}
+}
+
+// hugeStringer is a fmt.Stringer with a huge output.
+type hugeStringer struct{}
+
+func (hugeStringer) String() string {
+	return strings.Repeat("x", 1<<20)
+}
+
+func TestWithMaxValueLength(t *testing.T) {
+	tests := []struct {
+		opts      []Option
+		expectLen int
+	}{
+		{nil, defaultMaxValueLength},
+		{[]Option{WithMaxValueLength(100)}, 100},
+		{[]Option{WithMaxValueLength(0)}, 1 << 20},
+	}
+
+	for i, test := range tests {
+		record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+		record.AddAttrs(
+			slog.Any("huge", hugeStringer{}),
+			slog.Any("nested", map[string]any{"huge": hugeStringer{}}),
+			slog.Any("tag_huge", hugeStringer{}),
+		)
+		event := CaptureToEvent(context.Background(), record, append(test.opts, WithTagPrefix("tag_"))...)
+		if event == nil {
+			t.Fatalf("test %d: expect an event", i)
+		}
+
+		huge, _ := event.Contexts["slog"]["huge"].(string)
+		nested, _ := event.Contexts["slog"]["nested"].(map[string]any)
+		nestedHuge, _ := nested["huge"].(string)
+		for name, value := range map[string]string{"context": huge, "nested": nestedHuge, "tag": event.Tags["huge"]} {
+			if len(value) != test.expectLen {
+				t.Errorf("test %d: expect %s value of %d bytes, got: %d", i, name, test.expectLen, len(value))
+			}
+		}
+	}
+}
+
+type shardKey struct{}
+
+func TestWithTagFunc(t *testing.T) {
+	handler := newTestHandler([]slog.Level{slog.LevelError}, WithTagPrefix("tag_"), WithTagFunc(func(ctx context.Context, record slog.Record) map[string]string {
+		shard, _ := ctx.Value(shardKey{}).(string)
+		return map[string]string{"shard": shard, "message": record.Message}
+	}))
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	ctx = context.WithValue(ctx, shardKey{}, "eu-3")
+
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	record.AddAttrs(slog.String("tag_message", "from attr"))
+	if err := handler.Handle(ctx, record); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("expect 1 event, got: %d", len(events))
+	}
+	if shard := events[0].Tags["shard"]; shard != "eu-3" {
+		t.Errorf("expect tag shard %q, got: %q", "eu-3", shard)
+	}
+	if message := events[0].Tags["message"]; message != "from attr" {
+		t.Errorf("expect tag attribute to take precedence, got: %q", message)
+	}
+}
+
+// failingHandler is a slog.Handler failing to handle records.
+type failingHandler struct {
+	slog.Handler
+}
+
+func (failingHandler) Handle(context.Context, slog.Record) error {
+	return errors.New("disk full")
+}
+
+func TestWithReportInnerErrors(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	handler := NewSentryHandler(failingHandler{slog.NewTextHandler(io.Discard, nil)}, []slog.Level{slog.LevelError}, WithReportInnerErrors(true))
+
+	for _, msg := range []string{"first", "second"} {
+		if err := handler.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelError, msg, 0)); err == nil || err.Error() != "disk full" {
+			t.Fatalf("expect the inner handler error, got: %v", err)
+		}
+	}
+
+	events := transport.Events()
+	if len(events) != 2 {
+		t.Fatalf("expect 2 events, got: %d", len(events))
+	}
+	breadcrumbs := events[1].Breadcrumbs
+	if len(breadcrumbs) != 1 {
+		t.Fatalf("expect 1 breadcrumb, got: %d", len(breadcrumbs))
+	}
+	if expect := `slog: handle "first": disk full`; breadcrumbs[0].Message != expect || breadcrumbs[0].Level != sentry.LevelError {
+		t.Errorf("expect error breadcrumb %q, got: %s %q", expect, breadcrumbs[0].Level, breadcrumbs[0].Message)
+	}
+}
+
+func TestWithTagPrefixes(t *testing.T) {
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	record.AddAttrs(
+		slog.String("t_region", "eu"),
+		slog.String("tag_tier", "gold"),
+		slog.String("tag_t_x", "first match"),
+		slog.String("other", "value"),
+	)
+
+	event := CaptureToEvent(context.Background(), record, WithTagPrefixes("tag_", "t_"))
+	if event == nil {
+		t.Fatal("expect an event")
+	}
+	expect := map[string]string{"region": "eu", "tier": "gold", "t_x": "first match"}
+	if !reflect.DeepEqual(event.Tags, expect) {
+		t.Errorf("expect tags: %v, got: %v", expect, event.Tags)
+	}
+	if value := event.Contexts["slog"]["other"]; value != "value" {
+		t.Errorf("expect context other %q, got: %v", "value", value)
+	}
+}
+
+func TestWithTagPrefixesCopiesPrefixes(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	prefixes := []string{"tag_"}
+	handler := newTestHandler([]slog.Level{slog.LevelError}, WithTagPrefixes(prefixes...))
+	prefixes[0] = "t_"
+
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	record.AddAttrs(slog.String("tag_region", "eu"))
+	if err := handler.Handle(ctx, record); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("expect 1 event, got: %d", len(events))
+	}
+	if tag := events[0].Tags["region"]; tag != "eu" {
+		t.Errorf("expect changing the prefixes afterwards to have no effect, got tags: %v", events[0].Tags)
+	}
+}
+
+// timeoutError is an error type not wrapping other errors.
+type timeoutError struct{}
+
+func (*timeoutError) Error() string { return "timeout" }
+
+func TestWithErrorTypeTag(t *testing.T) {
+	tests := []struct {
+		err        error
+		expectType string
+	}{
+		{fmt.Errorf("connect: %w", &timeoutError{}), "*slogsentry.timeoutError"},
+		{&net.OpError{Op: "dial", Err: errors.New("refused")}, "*errors.errorString"},
+	}
+
+	for i, test := range tests {
+		record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+		record.AddAttrs(slog.Any("err", test.err))
+		event := CaptureToEvent(context.Background(), record, WithErrorTypeTag("error_type"))
+		if event == nil {
+			t.Fatalf("test %d: expect an event", i)
+		}
+		if tag := event.Tags["error_type"]; tag != test.expectType {
+			t.Errorf("test %d: expect tag error_type %q, got: %q", i, test.expectType, tag)
+		}
+	}
+
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, "the message", 0)
+	if tag, ok := CaptureToEvent(context.Background(), record, WithErrorTypeTag("error_type")).Tags["error_type"]; ok {
+		t.Errorf("expect no tag for messages, got: %q", tag)
+	}
+
+	// The error type tag counts towards the tag limit.
+	record = slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	record.AddAttrs(slog.Any("err", errors.New("failed")))
+	for n := 0; n < 5; n++ {
+		record.AddAttrs(slog.Int(fmt.Sprintf("tag_t%02d", n), n))
+	}
+	event := CaptureToEvent(context.Background(), record, WithErrorTypeTag("error_type"), WithTagPrefix("tag_"), WithMaxTags(5))
+	if n := len(event.Tags); n != 5 {
+		t.Errorf("expect 5 tags, got: %d", n)
+	}
+	if tag, ok := event.Tags["error_type"]; !ok {
+		t.Errorf("expect tag error_type, got: %v", event.Tags)
+	} else if tag != "*errors.errorString" {
+		t.Errorf("expect tag error_type %q, got: %q", "*errors.errorString", tag)
+	}
+}
+
+func TestWithEnvironment(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{Environment: "client"})
+	for _, opts := range [][]Option{nil, {WithEnvironment("staging")}} {
+		if err := newTestHandler([]slog.Level{slog.LevelError}, opts...).Handle(ctx, slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)); err != nil {
+			t.Fatalf("error from Handle: %s", err)
+		}
+	}
+
+	events := transport.Events()
+	if len(events) != 2 {
+		t.Fatalf("expect 2 events, got: %d", len(events))
+	}
+	if events[0].Environment != "client" {
+		t.Errorf("expect client environment %q, got: %q", "client", events[0].Environment)
+	}
+	if events[1].Environment != "staging" {
+		t.Errorf("expect environment %q, got: %q", "staging", events[1].Environment)
+	}
+}
+
+func TestSetDefaultOptions(t *testing.T) {
+	SetDefaultOptions(WithEnvironment("production"), WithTagPrefix("tag_"))
+	t.Cleanup(func() { SetDefaultOptions() })
+
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	record.AddAttrs(slog.String("tag_region", "eu"))
+
+	event := CaptureToEvent(context.Background(), record)
+	if event.Environment != "production" || event.Tags["region"] != "eu" {
+		t.Errorf("expect the default options to apply, got environment %q and tags %v", event.Environment, event.Tags)
+	}
+
+	event = CaptureToEvent(context.Background(), record, WithEnvironment("staging"))
+	if event.Environment != "staging" {
+		t.Errorf("expect explicit options to override the defaults, got: %q", event.Environment)
+	}
+
+	SetDefaultOptions()
+	if event := CaptureToEvent(context.Background(), record); event.Environment != "" {
+		t.Errorf("expect no environment after clearing the defaults, got: %q", event.Environment)
+	}
+}
+
+func TestWithErrorDedup(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	handler := newTestHandler([]slog.Level{slog.LevelError},
+		WithErrorDedup(time.Minute),
+		WithClock(func() time.Time { return now }),
+	)
+
+	root := &timeoutError{}
+	tests := []struct {
+		advance      time.Duration
+		err          error
+		expectEvents int
+	}{
+		{0, fmt.Errorf("attempt 1: %w", root), 1},
+		{time.Second, fmt.Errorf("retry: attempt 2: %w", root), 1},
+		{time.Second, errors.New("other"), 2},
+		{time.Second, nil, 3},
+		{time.Minute, fmt.Errorf("attempt 3: %w", root), 4},
+	}
+
+	for i, test := range tests {
+		now = now.Add(test.advance)
+		record := slog.NewRecord(now, slog.LevelError, "the message", 0)
+		if test.err != nil {
+			record.AddAttrs(slog.Any("err", test.err))
+		}
+		if err := handler.Handle(ctx, record); err != nil {
+			t.Fatalf("test %d: error from Handle: %s", i, err)
+		}
+		if n := len(transport.Events()); n != test.expectEvents {
+			t.Errorf("test %d: expect %d events, got: %d", i, test.expectEvents, n)
+		}
+	}
+}
+
+func TestWithErrorDedupRateLimited(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	handler := newTestHandler([]slog.Level{slog.LevelError},
+		WithRateLimit(1, time.Minute),
+		WithErrorDedup(time.Hour),
+		WithClock(func() time.Time { return now }),
+	)
+
+	tests := []struct {
+		advance      time.Duration
+		err          error
+		expectEvents int
+	}{
+		{0, errors.New("first"), 1},
+		{time.Second, errors.New("second"), 1},
+		{time.Minute, errors.New("second"), 2},
+		{time.Minute, errors.New("second"), 2},
+	}
+
+	for i, test := range tests {
+		now = now.Add(test.advance)
+		record := slog.NewRecord(now, slog.LevelError, "the message", 0)
+		record.AddAttrs(slog.Any("err", test.err))
+		if err := handler.Handle(ctx, record); err != nil {
+			t.Fatalf("test %d: error from Handle: %s", i, err)
+		}
+		if n := len(transport.Events()); n != test.expectEvents {
+			t.Errorf("test %d: expect %d events, got: %d", i, test.expectEvents, n)
+		}
+	}
+}
+
+func TestWithAllLevels(t *testing.T) {
+	for _, levels := range [][]slog.Level{nil, {slog.LevelError}} {
+		ctx, transport := newTestContext(t, sentry.ClientOptions{})
+		handler := newTestHandler(levels, WithAllLevels())
+
+		standard := []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError}
+		for _, level := range standard {
+			if err := handler.Handle(ctx, slog.NewRecord(time.Now(), level, "the message", 0)); err != nil {
+				t.Fatalf("error from Handle: %s", err)
+			}
+		}
+		if n := len(transport.Events()); n != len(standard) {
+			t.Errorf("levels %v: expect %d events, got: %d", levels, len(standard), n)
+		}
+	}
+}
+
+func TestWithAllLevelsBetweenLevels(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	handler := newTestHandler(nil, WithAllLevels())
+
+	for _, level := range []slog.Level{slog.LevelInfo + 2, slog.LevelError + 2} {
+		if err := handler.Handle(ctx, slog.NewRecord(time.Now(), level, "the message", 0)); err != nil {
+			t.Fatalf("error from Handle: %s", err)
+		}
+	}
+
+	events := transport.Events()
+	if len(events) != 2 {
+		t.Fatalf("expect 2 events, got: %d", len(events))
+	}
+	if events[0].Level != sentry.LevelInfo || events[0].Message != "the message" || len(events[0].Exception) != 0 {
+		t.Errorf("expect an info message between info and warn, got: %s message %q with %d exceptions", events[0].Level, events[0].Message, len(events[0].Exception))
+	}
+	if events[1].Level != sentry.LevelError || len(events[1].Exception) == 0 {
+		t.Errorf("expect an error exception above error, got: %s with %d exceptions", events[1].Level, len(events[1].Exception))
+	}
+}
+
+func TestWithAllLevelsBetweenLevelsEscalation(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	handler := newTestHandler(nil, WithAllLevels(), WithWarnEscalation(2, time.Minute))
+
+	for i := 0; i < 2; i++ {
+		if err := handler.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelWarn+1, "slow query", 0)); err != nil {
+			t.Fatalf("error from Handle: %s", err)
+		}
+		if n := len(transport.Events()); n != i {
+			t.Errorf("record %d: expect %d events, got: %d", i, i, n)
+		}
+	}
+}
+
+func TestNewSentryHandlerCopiesLevels(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	levels := []slog.Level{slog.LevelError}
+	handler := newTestHandler(levels)
+	levels[0] = slog.LevelInfo
+
+	if err := handler.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+	if n := len(transport.Events()); n != 1 {
+		t.Errorf("expect changing the levels afterwards to have no effect, got %d events", n)
+	}
+}
+
+type userKey struct{}
+
+func TestWithUserFromContext(t *testing.T) {
+	contextUser := &sentry.User{ID: "7", Username: "bob", Data: map[string]string{"plan": "gold"}}
+	handler := newTestHandler([]slog.Level{slog.LevelError}, WithUserPrefix("user_"), WithUserFromContext(func(ctx context.Context) *sentry.User {
+		user, _ := ctx.Value(userKey{}).(*sentry.User)
+		return user
+	}))
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	userCtx := context.WithValue(ctx, userKey{}, contextUser)
+
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	if err := handler.Handle(userCtx, record); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+	record.AddAttrs(slog.String("user_email", "bob@example.com"), slog.String("user_plan", "silver"))
+	if err := handler.Handle(userCtx, record); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+	if err := handler.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelError, "no user", 0)); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+
+	events := transport.Events()
+	if len(events) != 3 {
+		t.Fatalf("expect 3 events, got: %d", len(events))
+	}
+	if !reflect.DeepEqual(events[0].User, *contextUser) {
+		t.Errorf("expect context user: %+v, got: %+v", *contextUser, events[0].User)
+	}
+	merged := sentry.User{ID: "7", Username: "bob", Email: "bob@example.com", Data: map[string]string{"plan": "silver"}}
+	if !reflect.DeepEqual(events[1].User, merged) {
+		t.Errorf("expect merged user: %+v, got: %+v", merged, events[1].User)
+	}
+	if contextUser.Data["plan"] != "gold" {
+		t.Error("expect the context user not to be modified")
+	}
+	if !events[2].User.IsEmpty() {
+		t.Errorf("expect no user, got: %+v", events[2].User)
+	}
+}
+
+func TestWithLevelTag(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	critical := slog.LevelError + 4
+	handler := newTestHandler([]slog.Level{slog.LevelWarn, slog.LevelError},
+		WithLevelTag(true),
+		WithPanicLevel(critical),
+		WithPanicHandler(func(context.Context, error) {}),
+	)
+
+	for _, level := range []slog.Level{slog.LevelWarn, slog.LevelError, critical} {
+		if err := handler.Handle(ctx, slog.NewRecord(time.Now(), level, "the message", 0)); err != nil {
+			t.Fatalf("error from Handle: %s", err)
+		}
+	}
+
+	events := transport.Events()
+	if len(events) != 3 {
+		t.Fatalf("expect 3 events, got: %d", len(events))
+	}
+	for i, expected := range []string{"WARN", "ERROR", "ERROR+4"} {
+		if tag := events[i].Tags["log_level"]; tag != expected {
+			t.Errorf("event %d: expect tag log_level %q, got: %q", i, expected, tag)
+		}
+	}
+}
+
+func TestWithErrorExtras(t *testing.T) {
+	for _, level := range []slog.Level{slog.LevelError, slog.LevelInfo} {
+		record := slog.NewRecord(time.Now(), level, "the message", 0)
+		record.AddAttrs(slog.String("some_attr", "yes"))
+
+		event := CaptureToEvent(context.Background(), record, WithErrorExtras(true))
+		if event == nil {
+			t.Fatalf("%s: expect an event", level)
+		}
+		if value := event.Contexts["slog"]["some_attr"]; value != "yes" {
+			t.Errorf("%s: expect context some_attr %q, got: %v", level, "yes", value)
+		}
+		value, ok := event.Extra["some_attr"]
+		if level == slog.LevelError && value != "yes" {
+			t.Errorf("%s: expect extra some_attr %q, got: %v", level, "yes", value)
+		}
+		if level == slog.LevelInfo && ok {
+			t.Errorf("%s: expect no extra some_attr, got: %v", level, value)
+		}
+	}
+}
+
+func TestWithEventModifier(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	handler := newTestHandler([]slog.Level{slog.LevelError}, WithEventModifier(func(_ context.Context, event *sentry.Event) {
+		event.Dist = "build-42"
+	}))
+
+	if err := handler.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("expect 1 event, got: %d", len(events))
+	}
+	if dist := events[0].Dist; dist != "build-42" {
+		t.Errorf("expect dist %q, got: %q", "build-42", dist)
+	}
+}
+
+func TestWithEventProcessor(t *testing.T) {
+	hub, transport := newTestHub(t, sentry.ClientOptions{})
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+	handler := newTestHandler([]slog.Level{slog.LevelError},
+		WithEventProcessor(func(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+			event.Tags["processed"] = "first"
+			return event
+		}),
+		WithEventProcessor(func(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+			event.Tags["processed"] += ",second"
+			return event
+		}),
+	)
+
+	if err := handler.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+	hub.CaptureMessage("not from the handler")
+
+	events := transport.Events()
+	if len(events) != 2 {
+		t.Fatalf("expect 2 events, got: %d", len(events))
+	}
+	if tag := events[0].Tags["processed"]; tag != "first,second" {
+		t.Errorf("expect tag processed %q, got: %q", "first,second", tag)
+	}
+	if tag, ok := events[1].Tags["processed"]; ok {
+		t.Errorf("expect the processors to apply to the handler only, got tag: %q", tag)
+	}
+}
+
+func TestWithWarnEscalation(t *testing.T) {
+	hub, transport := newTestHub(t, sentry.ClientOptions{})
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	handler := newTestHandler([]slog.Level{slog.LevelWarn, slog.LevelError},
+		WithWarnEscalation(3, time.Minute),
+		WithClock(func() time.Time { return now }),
+	)
+
+	tests := []struct {
+		advance      time.Duration
+		level        slog.Level
+		msg          string
+		expectEvents int
+	}{
+		{0, slog.LevelWarn, "slow query", 0},
+		{time.Second, slog.LevelWarn, "slow query", 0},
+		{time.Second, slog.LevelWarn, "other", 0},
+		{time.Second, slog.LevelError, "failed", 1},
+		{time.Second, slog.LevelWarn, "slow query", 2},
+		{time.Second, slog.LevelWarn, "slow query", 3},
+		{time.Minute, slog.LevelWarn, "slow query", 3},
+	}
+
+	for i, test := range tests {
+		now = now.Add(test.advance)
+		if err := handler.Handle(ctx, slog.NewRecord(now, test.level, test.msg, 0)); err != nil {
+			t.Fatalf("test %d: error from Handle: %s", i, err)
+		}
+		if n := len(transport.Events()); n != test.expectEvents {
+			t.Errorf("test %d: expect %d events, got: %d", i, test.expectEvents, n)
+		}
+	}
+
+	events := transport.Events()
+	if n := len(events[0].Breadcrumbs); n != 3 {
+		t.Errorf("expect 3 breadcrumbs on the error event, got: %d", n)
+	} else if msg := events[0].Breadcrumbs[0].Message; msg != "slow query" {
+		t.Errorf("expect breadcrumb %q, got: %q", "slow query", msg)
+	}
+}
+
+func TestWithFlushTimeout(t *testing.T) {
+	tests := []struct {
+		opts          []Option
+		expectTimeout time.Duration
+	}{
+		{nil, 2 * time.Second},
+		{[]Option{WithFlushTimeout(500 * time.Millisecond)}, 500 * time.Millisecond},
+	}
+
+	for i, test := range tests {
+		ctx, transport := newTestContext(t, sentry.ClientOptions{})
+		opts := append([]Option{WithPanicLevel(slog.LevelError), WithPanicHandler(func(context.Context, error) {})}, test.opts...)
+		handler := newTestHandler(nil, opts...)
+
+		if err := handler.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelError, "fatal", 0)); err != nil {
+			t.Fatalf("test %d: error from Handle: %s", i, err)
+		}
+		if timeouts := transport.FlushTimeouts(); !slices.Equal(timeouts, []time.Duration{test.expectTimeout}) {
+			t.Errorf("test %d: expect a flush with timeout %s, got: %v", i, test.expectTimeout, timeouts)
+		}
+	}
+}
+
+func TestWithFingerprintSplit(t *testing.T) {
+	tests := []struct {
+		sep               string
+		value             string
+		expectFingerprint []string
+	}{
+		{" ", "agi timeout  db", []string{"agi", "timeout", "db"}},
+		{" ", "agi,timeout", []string{"agi,timeout"}},
+		{"|", "agi | timeout", []string{"agi", "timeout"}},
+		{"", "agi, timeout", []string{"agi, timeout"}},
+	}
+
+	for i, test := range tests {
+		record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+		record.AddAttrs(slog.String("fingerprint", test.value))
+
+		event := CaptureToEvent(context.Background(), record, WithFingerprintSplit(test.sep))
+		if event == nil {
+			t.Fatalf("test %d: expect an event", i)
+		}
+		if !slices.Equal(event.Fingerprint, test.expectFingerprint) {
+			t.Errorf("test %d: expect: %q, got: %q", i, test.expectFingerprint, event.Fingerprint)
+		}
+	}
+}
+
+func TestWithSendAsLogs(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	handler := NewSentryHandler(
+		slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		[]slog.Level{slog.LevelError},
+		WithSendAsLogs(slog.LevelDebug, slog.LevelError),
+	)
+
+	for _, level := range []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelError} {
+		record := slog.NewRecord(time.Now(), level, "the message", 0)
+		record.AddAttrs(slog.Any("err", errors.New("the error")))
+		if err := handler.Handle(ctx, record); err != nil {
+			t.Fatalf("%s: error from Handle: %s", level, err)
+		}
+	}
+
+	// Until sentry-go has a logs API the logs fall back to message events.
+	events := transport.Events()
+	if len(events) != 2 {
+		t.Fatalf("expect 2 events, got: %d", len(events))
+	}
+	for i, expected := range []sentry.Level{sentry.LevelDebug, sentry.LevelError} {
+		event := events[i]
+		if event.Level != expected {
+			t.Errorf("event %d: expect level %q, got: %q", i, expected, event.Level)
+		}
+		if event.Message != "the message" || len(event.Exception) > 0 {
+			t.Errorf("event %d: expect a message event, got: %q with %d exceptions", i, event.Message, len(event.Exception))
+		}
+	}
+}
+
+func TestWithIgnoreErrors(t *testing.T) {
+	errIgnored := errors.New("ignored")
+	tests := []struct {
+		opt           Option
+		err           error
+		expectCapture bool
+	}{
+		{WithIgnoreErrors(), fmt.Errorf("query: %w", context.Canceled), false},
+		{WithIgnoreErrors(), fmt.Errorf("query: %w", context.DeadlineExceeded), false},
+		{WithIgnoreErrors(), errors.New("other"), true},
+		{WithIgnoreErrors(errIgnored), fmt.Errorf("wrapped: %w", errIgnored), false},
+		{WithIgnoreErrors(errIgnored), context.Canceled, true},
+		{WithIgnoreErrors(errIgnored), nil, true},
+	}
+
+	for i, test := range tests {
+		var buf bytes.Buffer
+		ctx, transport := newTestContext(t, sentry.ClientOptions{})
+		handler := NewSentryHandler(slog.NewTextHandler(&buf, nil), []slog.Level{slog.LevelError}, test.opt)
+
+		record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+		record.AddAttrs(slog.Any("err", test.err))
+		if err := handler.Handle(ctx, record); err != nil {
+			t.Fatalf("test %d: error from Handle: %s", i, err)
+		}
+		if captured := len(transport.Events()) == 1; captured != test.expectCapture {
+			t.Errorf("test %d: expect captured %t, got: %t", i, test.expectCapture, captured)
+		}
+		if !strings.Contains(buf.String(), "the message") {
+			t.Errorf("test %d: expect the record logged locally, got: %q", i, buf.String())
+		}
+	}
+}
+
+func TestWithIgnoreErrorTypes(t *testing.T) {
+	handler := newTestHandler([]slog.Level{slog.LevelError}, WithIgnoreErrorTypes((*timeoutError)(nil), (*net.OpError)(nil)))
+	tests := []struct {
+		err           error
+		expectCapture bool
+	}{
+		{fmt.Errorf("connect: %w", &timeoutError{}), false},
+		{&net.OpError{Op: "dial", Err: errors.New("refused")}, false},
+		{errors.New("other"), true},
+		{nil, true},
+	}
+
+	for i, test := range tests {
+		ctx, transport := newTestContext(t, sentry.ClientOptions{})
+		record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+		record.AddAttrs(slog.Any("err", test.err))
+		if err := handler.Handle(ctx, record); err != nil {
+			t.Fatalf("test %d: error from Handle: %s", i, err)
+		}
+		if captured := len(transport.Events()) == 1; captured != test.expectCapture {
+			t.Errorf("test %d: expect captured %t, got: %t", i, test.expectCapture, captured)
+		}
+	}
+}
+
+func TestWithIgnoreErrorTypesPanicsForNonErrors(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expect WithIgnoreErrorTypes to panic")
+		}
+	}()
+	WithIgnoreErrorTypes("not an error")
+}
+
+func TestWithDist(t *testing.T) {
+	tests := []struct {
+		opts       []Option
+		attrs      []slog.Attr
+		expectDist string
+	}{
+		{nil, nil, "client"},
+		{[]Option{WithDist("42")}, nil, "42"},
+		{[]Option{WithDist("42")}, []slog.Attr{slog.String("dist", "43")}, "43"},
+		{nil, []slog.Attr{slog.Int("dist", 44)}, "44"},
+	}
+
+	for i, test := range tests {
+		ctx, transport := newTestContext(t, sentry.ClientOptions{Dist: "client"})
+		record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+		record.AddAttrs(test.attrs...)
+		if err := newTestHandler([]slog.Level{slog.LevelError}, test.opts...).Handle(ctx, record); err != nil {
+			t.Fatalf("test %d: error from Handle: %s", i, err)
+		}
+
+		events := transport.Events()
+		if len(events) != 1 {
+			t.Fatalf("test %d: expect 1 event, got: %d", i, len(events))
+		}
+		if dist := events[0].Dist; dist != test.expectDist {
+			t.Errorf("test %d: expect dist %q, got: %q", i, test.expectDist, dist)
+		}
+		if _, ok := events[0].Contexts["slog"]["dist"]; ok {
+			t.Errorf("test %d: expect no dist context", i)
+		}
+	}
+}
+
+func TestWithGroupTagNamespace(t *testing.T) {
+	for _, enable := range []bool{false, true} {
+		ctx, transport := newTestContext(t, sentry.ClientOptions{})
+		handler := newTestHandler([]slog.Level{slog.LevelError}, WithTagPrefix("tag_"), WithGroupTagNamespace(enable)).
+			WithAttrs([]slog.Attr{slog.String("tag_service", "api")}).
+			WithGroup("http").
+			WithAttrs([]slog.Attr{slog.String("tag_method", "GET")}).
+			WithGroup("auth")
+
+		record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+		record.AddAttrs(slog.String("tag_user", "alice"), Tag("scheme", "bearer"))
+		if err := handler.Handle(ctx, record); err != nil {
+			t.Fatalf("%t: error from Handle: %s", enable, err)
+		}
+
+		events := transport.Events()
+		if len(events) != 1 {
+			t.Fatalf("%t: expect 1 event, got: %d", enable, len(events))
+		}
+		expected := map[string]string{"service": "api", "method": "GET", "user": "alice", "scheme": "bearer"}
+		if enable {
+			expected = map[string]string{"service": "api", "http.method": "GET", "http.auth.user": "alice", "http.auth.scheme": "bearer"}
+		}
+		if tags := events[0].Tags; !reflect.DeepEqual(tags, expected) {
+			t.Errorf("%t: expect tags %v, got: %v", enable, expected, tags)
+		}
+	}
+}
+
+func TestWithErrorChainBreadcrumbs(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	handler := newTestHandler([]slog.Level{slog.LevelError}, WithErrorChainBreadcrumbs(true))
+
+	err := fmt.Errorf("handle order: %w", fmt.Errorf("query: %w", &timeoutError{}))
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	record.AddAttrs(slog.Any("err", err))
+	if err := handler.Handle(ctx, record); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("expect 1 event, got: %d", len(events))
+	}
+	var messages []string
+	for _, breadcrumb := range events[0].Breadcrumbs {
+		messages = append(messages, breadcrumb.Message)
+	}
+	expected := []string{"timeout", "query: timeout", "handle order: query: timeout"}
+	if !slices.Equal(messages, expected) {
+		t.Errorf("expect breadcrumbs %q, got: %q", expected, messages)
+	}
+	if typ := events[0].Breadcrumbs[0].Data["type"]; typ != "*slogsentry.timeoutError" {
+		t.Errorf("expect breadcrumb type %q, got: %v", "*slogsentry.timeoutError", typ)
+	}
+}
+
+func TestWithGroupAsContextSection(t *testing.T) {
+	for _, enable := range []bool{false, true} {
+		ctx, transport := newTestContext(t, sentry.ClientOptions{})
+		handler := newTestHandler([]slog.Level{slog.LevelError}, WithGroupAsContextSection(enable)).
+			WithAttrs([]slog.Attr{slog.String("service", "api")}).
+			WithGroup("db").
+			WithAttrs([]slog.Attr{slog.String("table", "orders")}).
+			WithGroup("query")
+
+		record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+		record.AddAttrs(slog.Int("rows", 3))
+		if err := handler.Handle(ctx, record); err != nil {
+			t.Fatalf("%t: error from Handle: %s", enable, err)
+		}
+
+		events := transport.Events()
+		if len(events) != 1 {
+			t.Fatalf("%t: expect 1 event, got: %d", enable, len(events))
+		}
+		expected := map[string]sentry.Context{
+			"slog": {"service": "api", "table": "orders", "rows": "3"},
+		}
+		if enable {
+			expected = map[string]sentry.Context{
+				"slog": {"service": "api"},
+				"db":   {"table": "orders", "rows": "3"},
+			}
+		}
+		for name, context := range expected {
+			if !reflect.DeepEqual(events[0].Contexts[name], context) {
+				t.Errorf("%t: expect %s context %v, got: %v", enable, name, context, events[0].Contexts[name])
+			}
+		}
+		if _, ok := events[0].Contexts["db"]; !enable && ok {
+			t.Errorf("%t: expect no db context", enable)
+		}
+	}
+}
+
+func TestWithGroupInMessage(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	handler := newTestHandler([]slog.Level{slog.LevelWarn, slog.LevelError}, WithGroupInMessage(true))
+	grouped := handler.WithGroup("http").WithGroup("auth")
+
+	for _, test := range []struct {
+		handler slog.Handler
+		level   slog.Level
+	}{
+		{handler, slog.LevelWarn},
+		{grouped, slog.LevelWarn},
+		{grouped, slog.LevelError},
+	} {
+		if err := test.handler.Handle(ctx, slog.NewRecord(time.Now(), test.level, "login failed", 0)); err != nil {
+			t.Fatalf("error from Handle: %s", err)
+		}
+	}
+
+	events := transport.Events()
+	if len(events) != 3 {
+		t.Fatalf("expect 3 events, got: %d", len(events))
+	}
+	if msg := events[0].Message; msg != "login failed" {
+		t.Errorf("expect message without groups %q, got: %q", "login failed", msg)
+	}
+	if msg := events[1].Message; msg != "[http.auth] login failed" {
+		t.Errorf("expect message %q, got: %q", "[http.auth] login failed", msg)
+	}
+	if value := events[2].Exception[len(events[2].Exception)-1].Value; value != "login failed" {
+		t.Errorf("expect exception without groups %q, got: %q", "login failed", value)
+	}
+}
+
+func TestWithExtraKeys(t *testing.T) {
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	record.AddAttrs(slog.String("order_id", "42"), slog.String("some_attr", "yes"))
+
+	event := CaptureToEvent(context.Background(), record, WithExtraKeys("order_id"))
+	if event == nil {
+		t.Fatal("expect an event")
+	}
+	if value := event.Extra["order_id"]; value != "42" {
+		t.Errorf("expect extra order_id %q, got: %v", "42", value)
+	}
+	if _, ok := event.Contexts["slog"]["order_id"]; ok {
+		t.Error("expect no context order_id")
+	}
+	if value := event.Contexts["slog"]["some_attr"]; value != "yes" {
+		t.Errorf("expect context some_attr %q, got: %v", "yes", value)
+	}
+	if _, ok := event.Extra["some_attr"]; ok {
+		t.Error("expect no extra some_attr")
+	}
+}
+
+func TestWithKeepDefaultKeys(t *testing.T) {
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	record.AddAttrs(
+		slog.String("source", "billing.go:42"),
+		slog.String("level", "custom"),
+		slog.Any("err", errors.New("the error")),
+	)
+
+	event := CaptureToEvent(context.Background(), record, WithKeepDefaultKeys("source", "err"))
+	if event == nil {
+		t.Fatal("expect an event")
+	}
+	if value := event.Contexts["slog"]["source"]; value != "billing.go:42" {
+		t.Errorf("expect context source %q, got: %v", "billing.go:42", value)
+	}
+	if value, ok := event.Contexts["slog"]["level"]; ok {
+		t.Errorf("expect no context level, got: %v", value)
+	}
+	if value, ok := event.Contexts["slog"]["err"]; ok {
+		t.Errorf("expect no context err, got: %v", value)
+	}
+	if value := event.Exception[len(event.Exception)-1].Value; value != "the message: the error" {
+		t.Errorf("expect the error to be kept, got: %q", value)
+	}
+
+	handler := newTestHandler(nil, WithKeepDefaultKeys("source"))
+	if keys := handler.Config().IgnoredKeys; !slices.Equal(keys, []string{"time", "level", "msg"}) {
+		t.Errorf("expect ignored keys [time level msg], got: %q", keys)
+	}
+}
+
+func TestWithMessageAsFingerprint(t *testing.T) {
+	tests := []struct {
+		attrs             []slog.Attr
+		expectFingerprint []string
+	}{
+		{[]slog.Attr{slog.Any("err", fmt.Errorf("order %d: %w", 42, &timeoutError{}))}, []string{"charge failed"}},
+		{[]slog.Attr{slog.Any("err", fmt.Errorf("order %d: %w", 43, &timeoutError{}))}, []string{"charge failed"}},
+		{nil, []string{"charge failed"}},
+		{[]slog.Attr{slog.String("fingerprint", "payments")}, []string{"payments"}},
+	}
+
+	for i, test := range tests {
+		record := slog.NewRecord(time.Now(), slog.LevelError, "charge failed", 0)
+		record.AddAttrs(test.attrs...)
+
+		event := CaptureToEvent(context.Background(), record, WithMessageAsFingerprint(true))
+		if event == nil {
+			t.Fatalf("test %d: expect an event", i)
+		}
+		if !slices.Equal(event.Fingerprint, test.expectFingerprint) {
+			t.Errorf("test %d: expect fingerprint %q, got: %q", i, test.expectFingerprint, event.Fingerprint)
+		}
+	}
+}
+
+func TestWithMessageFormatter(t *testing.T) {
+	upper := WithMessageFormatter(func(_ context.Context, record slog.Record, err error) string {
+		return strings.ToUpper(record.Message)
+	})
+	tests := []struct {
+		level         slog.Level
+		err           error
+		expectMessage string
+	}{
+		{slog.LevelWarn, nil, "LOGIN FAILED"},
+		{slog.LevelError, errors.New("the error"), "LOGIN FAILED: the error"},
+	}
+
+	for i, test := range tests {
+		record := slog.NewRecord(time.Now(), test.level, "login failed", 0)
+		if test.err != nil {
+			record.AddAttrs(slog.Any("err", test.err))
+		}
+
+		event := CaptureToEvent(context.Background(), record, upper)
+		if event == nil {
+			t.Fatalf("test %d: expect an event", i)
+		}
+		msg := event.Message
+		if len(event.Exception) > 0 {
+			msg = event.Exception[len(event.Exception)-1].Value
+		}
+		if msg != test.expectMessage {
+			t.Errorf("test %d: expect message %q, got: %q", i, test.expectMessage, msg)
+		}
+	}
+
+	var panicErr error
+	handler := newTestHandler(nil, upper, WithPanicLevel(slog.LevelError), WithPanicHandler(func(_ context.Context, err error) { panicErr = err }))
+	ctx, _ := newTestContext(t, sentry.ClientOptions{})
+	if err := handler.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelError, "fatal", 0)); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+	if panicErr == nil || panicErr.Error() != "FATAL" {
+		t.Errorf("expect panic with %q, got: %v", "FATAL", panicErr)
+	}
+}
+
+func TestWithTagGroup(t *testing.T) {
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	record.AddAttrs(
+		slog.Group("tags", slog.String("region", "eu"), slog.Any("tier", userValuer{})),
+		slog.Group("other", slog.String("a", "b")),
+	)
+
+	event := CaptureToEvent(context.Background(), record, WithTagGroup("tags"))
+	if event == nil {
+		t.Fatal("expect an event")
+	}
+	if expected := map[string]string{"region": "eu", "tier": "user-7"}; !reflect.DeepEqual(event.Tags, expected) {
+		t.Errorf("expect tags %v, got: %v", expected, event.Tags)
+	}
+	if _, ok := event.Contexts["slog"]["tags"]; ok {
+		t.Error("expect no tags context")
+	}
+	if _, ok := event.Contexts["slog"]["other"]; !ok {
+		t.Error("expect other groups in the context")
+	}
+}
+
+func TestWithGroupAsContextSectionInlineGroups(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	handler := newTestHandler([]slog.Level{slog.LevelError}, WithGroupAsContextSection(true))
+
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	record.AddAttrs(
+		slog.Group("db", slog.String("table", "orders")),
+		slog.Group("cache", slog.Bool("hit", false), slog.Group("entry", slog.String("key", "k"))),
+		slog.String("service", "api"),
+	)
+	if err := handler.WithGroup("db").WithAttrs([]slog.Attr{slog.String("driver", "pgx")}).Handle(ctx, slog.NewRecord(time.Now(), slog.LevelError, "grouped", 0)); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+	if err := handler.Handle(ctx, record); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+
+	events := transport.Events()
+	if len(events) != 2 {
+		t.Fatalf("expect 2 events, got: %d", len(events))
+	}
+	if expected := (sentry.Context{"driver": "pgx"}); !reflect.DeepEqual(events[0].Contexts["db"], expected) {
+		t.Errorf("expect db context %v, got: %v", expected, events[0].Contexts["db"])
+	}
+	for name, expected := range map[string]sentry.Context{
+		"db":    {"table": "orders"},
+		"cache": {"hit": "false", "entry": map[string]any{"key": "k"}},
+		"slog":  {"service": "api"},
+	} {
+		if !reflect.DeepEqual(events[1].Contexts[name], expected) {
+			t.Errorf("expect %s context %v, got: %v", name, expected, events[1].Contexts[name])
+		}
+	}
+}
+
+func TestWithStacktraceMinLevel(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{AttachStacktrace: true})
+	handler := newTestHandler([]slog.Level{slog.LevelInfo, slog.LevelWarn, slog.LevelError}, WithStacktraceMinLevel(slog.LevelWarn))
+
+	for _, level := range []slog.Level{slog.LevelInfo, slog.LevelWarn, slog.LevelError} {
+		if err := handler.Handle(ctx, slog.NewRecord(time.Now(), level, "the message", 0)); err != nil {
+			t.Fatalf("%s: error from Handle: %s", level, err)
+		}
+	}
+
+	events := transport.Events()
+	if len(events) != 3 {
+		t.Fatalf("expect 3 events, got: %d", len(events))
+	}
+	if n := len(events[0].Threads); n != 0 {
+		t.Errorf("expect no stacktrace for info, got %d threads", n)
+	}
+	if len(events[1].Threads) != 1 || events[1].Threads[0].Stacktrace == nil {
+		t.Errorf("expect a stacktrace for warn, got: %+v", events[1].Threads)
+	}
+	if exception := events[2].Exception; len(exception) == 0 || exception[len(exception)-1].Stacktrace == nil {
+		t.Errorf("expect an exception with a stacktrace for error, got: %+v", exception)
+	}
+}
+
+func TestHooksReceiveContext(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	ctx = context.WithValue(ctx, tenantKey{}, "acme")
+
+	seen := map[string]any{}
+	handler := newTestHandler([]slog.Level{slog.LevelError},
+		WithMessageFormatter(func(ctx context.Context, record slog.Record, _ error) string {
+			seen["formatter"] = ctx.Value(tenantKey{})
+			return record.Message
+		}),
+		WithEventModifier(func(ctx context.Context, event *sentry.Event) {
+			seen["modifier"] = ctx.Value(tenantKey{})
+		}),
+		WithBeforeCapture(func(ctx context.Context, event *sentry.Event, _ slog.Record) *sentry.Event {
+			seen["before"] = ctx.Value(tenantKey{})
+			event.Tags["tenant"], _ = ctx.Value(tenantKey{}).(string)
+			return event
+		}),
+		WithOnCapture(func(ctx context.Context, _ slog.Record, _ CaptureMode, _ *sentry.EventID) {
+			seen["on"] = ctx.Value(tenantKey{})
+		}),
+	)
+
+	if err := handler.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+
+	for _, hook := range []string{"formatter", "modifier", "before", "on"} {
+		if value := seen[hook]; value != "acme" {
+			t.Errorf("expect hook %s to read %q from the context, got: %v", hook, "acme", value)
+		}
+	}
+	if events := transport.Events(); len(events) != 1 || events[0].Tags["tenant"] != "acme" {
+		t.Errorf("expect 1 event with tag tenant %q, got: %v", "acme", events)
+	}
+}
+
+func TestWithDisableContext(t *testing.T) {
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	record.AddAttrs(
+		slog.String("email", "someone@example.com"),
+		slog.String("tag_region", "eu"),
+		slog.Any("err", errors.New("the error")),
+	)
+
+	event := CaptureToEvent(context.Background(), record, WithDisableContext(true), WithTagPrefix("tag_"))
+	if event == nil {
+		t.Fatal("expect an event")
+	}
+	if _, ok := event.Contexts["slog"]; ok {
+		t.Errorf("expect no slog context, got: %v", event.Contexts["slog"])
+	}
+	if tag := event.Tags["region"]; tag != "eu" {
+		t.Errorf("expect tag region %q, got: %q", "eu", tag)
+	}
+	if len(event.Exception) == 0 || event.Exception[len(event.Exception)-1].Value != "the message: the error" {
+		t.Errorf("expect the error as exception, got: %v", event.Exception)
+	}
+}
+
+func TestWithDisableContextExtra(t *testing.T) {
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	record.AddAttrs(
+		slog.String("error", "secret text"),
+		slog.Any("valuer", panicValuer{}),
+		slog.String("email", "someone@example.com"),
+	)
+
+	for _, opt := range []Option{WithUseExtra(true), WithErrorExtras(true)} {
+		event := CaptureToEvent(context.Background(), record, WithDisableContext(true), opt)
+		if event == nil {
+			t.Fatal("expect an event")
+		}
+		if len(event.Extra) != 0 {
+			t.Errorf("expect no extra data, got: %v", event.Extra)
+		}
+		if _, ok := event.Contexts["slog"]; ok {
+			t.Errorf("expect no slog context, got: %v", event.Contexts["slog"])
+		}
+		if tag := event.Tags["_slog_attr_error"]; tag != "valuer" {
+			t.Errorf("expect tag _slog_attr_error %q, got: %q", "valuer", tag)
+		}
+	}
+}
+
+func TestWithFilter(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	inner := &countingHandler{Handler: slog.Default().Handler(), counts: map[string]int{}}
+	handler := NewSentryHandler(inner, []slog.Level{slog.LevelWarn}, WithFilter(func(_ context.Context, record slog.Record) bool {
+		return !strings.Contains(record.Message, "healthcheck")
+	}))
+
+	logger := slog.New(handler)
+	logger.WarnContext(ctx, "healthcheck failed")
+	logger.WarnContext(ctx, "the message")
+
+	events := transport.Events()
+	if len(events) != 1 || events[0].Message != "the message" {
+		t.Fatalf("expect only the message event, got: %v", events)
+	}
+	if count := inner.Count("healthcheck failed"); count != 1 {
+		t.Errorf("expect the filtered out record handled once by the inner handler, got: %d", count)
+	}
+}
+
+func TestWithLevelNameMapping(t *testing.T) {
+	const levelNotice = slog.Level(2)
+	nameLevels := func(groups []string, attr slog.Attr) slog.Attr {
+		if level, ok := attr.Value.Any().(slog.Level); ok && attr.Key == slog.LevelKey && level == levelNotice {
+			return slog.String(slog.LevelKey, "NOTICE")
+		}
+		return attr
+	}
+
+	record := slog.NewRecord(time.Now(), levelNotice, "the message", 0)
+	event := CaptureToEvent(context.Background(), record,
+		WithReplaceAttr(nameLevels),
+		WithLevelNameMapping(map[string]sentry.Level{"NOTICE": sentry.LevelInfo}),
+	)
+	if event == nil {
+		t.Fatal("expect an event")
+	}
+	if event.Level != sentry.LevelInfo || event.Message != "the message" || len(event.Exception) != 0 {
+		t.Errorf("expect info message %q, got: %s message %q with %d exceptions", "the message", event.Level, event.Message, len(event.Exception))
+	}
+
+	record = slog.NewRecord(time.Now(), slog.LevelWarn, "the message", 0)
+	event = CaptureToEvent(context.Background(), record,
+		WithLevelNameMapping(map[string]sentry.Level{"WARN": sentry.LevelWarning}),
+	)
+	if event.Level != sentry.LevelWarning {
+		t.Errorf("expect level %q by the String form, got: %q", sentry.LevelWarning, event.Level)
+	}
+}
+
+func TestWithLevelNameMappingCopiesMapping(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	mapping := map[string]sentry.Level{"WARN": sentry.LevelFatal}
+	handler := newTestHandler([]slog.Level{slog.LevelWarn}, WithLevelNameMapping(mapping))
+	mapping["WARN"] = sentry.LevelDebug
+
+	if err := handler.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelWarn, "the message", 0)); err != nil {
+		t.Fatalf("error from Handle: %s", err)
+	}
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("expect 1 event, got: %d", len(events))
+	}
+	if events[0].Level != sentry.LevelFatal {
+		t.Errorf("expect changing the mapping afterwards to have no effect, got level: %q", events[0].Level)
+	}
+}
+
+func TestWithStringErrors(t *testing.T) {
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	record.AddAttrs(slog.String("error", "something failed"))
+
+	event := CaptureToEvent(context.Background(), record, WithStringErrors(true))
+	if event == nil {
+		t.Fatal("expect an event")
+	}
+	exceptions := event.Exception
+	if len(exceptions) < 2 || exceptions[len(exceptions)-1].Value != "the message: something failed" {
+		t.Errorf("expect the string as error, got: %v", exceptions)
+	}
+	if _, ok := event.Contexts["slog"]["error"]; ok {
+		t.Error("expect no error in the slog context")
+	}
+
+	event = CaptureToEvent(context.Background(), record)
+	if value := event.Contexts["slog"]["error"]; value != "something failed" {
+		t.Errorf("expect context error %q by default, got: %v", "something failed", value)
+	}
+}
+
+func TestWithAlwaysException(t *testing.T) {
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, "the message", 0)
+
+	event := CaptureToEvent(context.Background(), record, WithAlwaysException(true))
+	if event == nil {
+		t.Fatal("expect an event")
+	}
+	if event.Level != sentry.LevelInfo || event.Message != "" {
+		t.Errorf("expect info event without message, got: %s message %q", event.Level, event.Message)
+	}
+	if len(event.Exception) != 1 || event.Exception[0].Value != "the message" || event.Exception[0].Type == "" {
+		t.Fatalf("expect exception %q, got: %v", "the message", event.Exception)
+	}
+	if event.Exception[0].Stacktrace == nil {
+		t.Error("expect a stack trace")
+	}
+}
+
+func TestWithAlwaysExceptionSendAsLogs(t *testing.T) {
+	record := slog.NewRecord(time.Now(), slog.LevelDebug, "the message", 0)
+
+	event := CaptureToEvent(context.Background(), record, WithAlwaysException(true), WithSendAsLogs(slog.LevelDebug))
+	if event == nil {
+		t.Fatal("expect an event")
+	}
+	if event.Level != sentry.LevelDebug || event.Message != "the message" || len(event.Exception) != 0 {
+		t.Errorf("expect debug message %q, got: %s message %q with %d exceptions", "the message", event.Level, event.Message, len(event.Exception))
+	}
+}
+
+func TestWithMaxContextSize(t *testing.T) {
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, "the message", 0)
+	record.AddAttrs(
+		slog.String("a", strings.Repeat("x", 100)),
+		slog.String("b", strings.Repeat("y", 100)),
+		slog.Group("request", slog.String("body", strings.Repeat("z", 200))),
+	)
+
+	event := CaptureToEvent(context.Background(), record, WithMaxContextSize(150), WithGroupAsContextSection(true))
+	if event == nil {
+		t.Fatal("expect an event")
+	}
+	if context := event.Contexts["slog"]; context["a"] == nil || context["b"] != nil || context["_trimmed"] != 1 {
+		t.Errorf("expect context without b, got: %v", context)
+	}
+	if section := event.Contexts["request"]; section["body"] != nil || section["_trimmed"] != 1 {
+		t.Errorf("expect request section without body, got: %v", section)
+	}
+}
+
+func TestWithMaxBreadcrumbDataSize(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	handler := newTestHandler([]slog.Level{slog.LevelWarn, slog.LevelError},
+		WithWarnEscalation(2, time.Minute),
+		WithMaxBreadcrumbDataSize(150),
+	)
+
+	logger := slog.New(handler)
+	logger.WarnContext(ctx, "slow query", "a", strings.Repeat("x", 100), "b", strings.Repeat("y", 100))
+	logger.ErrorContext(ctx, "failed")
+
+	events := transport.Events()
+	if len(events) != 1 || len(events[0].Breadcrumbs) != 1 {
+		t.Fatalf("expect 1 event with 1 breadcrumb, got: %v", events)
+	}
+	if data := events[0].Breadcrumbs[0].Data; data["a"] == nil || data["b"] != nil || data["_trimmed"] != 1 {
+		t.Errorf("expect breadcrumb data without b, got: %v", data)
+	}
+}
+
+func TestWithRouting(t *testing.T) {
+	type requestKey struct{}
+	ctx, defaultTransport := newTestContext(t, sentry.ClientOptions{})
+	ctx = context.WithValue(ctx, requestKey{}, "req-1")
+	paymentsHub, paymentsTransport := newTestHub(t, sentry.ClientOptions{})
+	searchHub, searchTransport := newTestHub(t, sentry.ClientOptions{})
+	handler := newTestHandler([]slog.Level{slog.LevelError}, WithTagPrefix("tag_"),
+		WithRouting(func(ctx context.Context, _ slog.Record, tags map[string]string) *sentry.Hub {
+			if id := ctx.Value(requestKey{}); id != "req-1" {
+				t.Errorf("expect the context of Handle with request %q, got: %v", "req-1", id)
+			}
+			team := tags["team"]
+			delete(tags, "team")
+			switch team {
+			case "payments":
+				return paymentsHub
+			case "search":
+				return searchHub
+			default:
+				return nil
+			}
+		}),
+	)
+
+	logger := slog.New(handler)
+	logger.ErrorContext(ctx, "charge failed", "tag_team", "payments")
+	logger.ErrorContext(ctx, "index failed", "tag_team", "search")
+	logger.ErrorContext(ctx, "unowned")
+
+	tests := []struct {
+		name      string
+		transport *transportMock
+		expect    string
+	}{
+		{"payments", paymentsTransport, "charge failed"},
+		{"search", searchTransport, "index failed"},
+		{"default", defaultTransport, "unowned"},
+	}
+	for _, test := range tests {
+		events := test.transport.Events()
+		if len(events) != 1 {
+			t.Errorf("%s hub: expect 1 event, got: %d", test.name, len(events))
+			continue
+		}
+		if msg := events[0].Exception[len(events[0].Exception)-1].Value; msg != test.expect {
+			t.Errorf("%s hub: expect %q, got: %q", test.name, test.expect, msg)
+		}
+		if _, ok := events[0].Tags["team"]; test.name != "default" && !ok {
+			t.Errorf("%s hub: expect tag team, got: %v", test.name, events[0].Tags)
+		}
+	}
+}
+
+func TestWithOnCaptureMode(t *testing.T) {
+	ctx, _ := newTestContext(t, sentry.ClientOptions{})
+	var modes []CaptureMode
+	handler := newTestHandler([]slog.Level{slog.LevelInfo, slog.LevelWarn, slog.LevelError},
+		WithWarnEscalation(2, time.Minute),
+		WithOnCapture(func(_ context.Context, _ slog.Record, mode CaptureMode, _ *sentry.EventID) {
+			modes = append(modes, mode)
+		}),
+	)
+
+	logger := slog.New(handler)
+	logger.InfoContext(ctx, "the message")
+	logger.WarnContext(ctx, "slow query")
+	logger.ErrorContext(ctx, "failed")
+	logger.ErrorContext(ctx, "skipped", "sentry_skip", true)
+
+	expected := []CaptureMode{ModeMessage, ModeBreadcrumb, ModeException}
+	if !slices.Equal(modes, expected) {
+		t.Errorf("expect modes %v, got: %v", expected, modes)
+	}
+}
+
+func TestWithCaptureStrategy(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	handler := newTestHandler([]slog.Level{slog.LevelInfo, slog.LevelError},
+		WithCaptureStrategy(func(_ context.Context, record slog.Record, mode CaptureMode) CaptureMode {
+			switch record.Message {
+			case "noisy":
+				return ModeBreadcrumb
+			case "dropped":
+				return ModeSkip
+			case "upgraded":
+				return ModeException
+			default:
+				return mode
+			}
+		}),
+	)
+
+	logger := slog.New(handler)
+	logger.InfoContext(ctx, "noisy")
+	logger.InfoContext(ctx, "dropped")
+	logger.InfoContext(ctx, "upgraded")
+	logger.InfoContext(ctx, "the message")
+
+	events := transport.Events()
+	if len(events) != 2 {
+		t.Fatalf("expect 2 events, got: %d", len(events))
+	}
+	if len(events[0].Exception) != 1 || events[0].Exception[0].Value != "upgraded" {
+		t.Errorf("expect exception %q, got: %v", "upgraded", events[0].Exception)
+	}
+	if events[1].Message != "the message" || len(events[1].Breadcrumbs) != 1 || events[1].Breadcrumbs[0].Message != "noisy" {
+		t.Errorf("expect message %q with breadcrumb %q, got: %q with %v", "the message", "noisy", events[1].Message, events[1].Breadcrumbs)
+	}
+}
+
+func TestWithCaptureStrategySkipped(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	var modes []CaptureMode
+	handler := newTestHandler([]slog.Level{slog.LevelError},
+		WithIgnoreErrors(context.Canceled),
+		WithCaptureStrategy(func(_ context.Context, _ slog.Record, mode CaptureMode) CaptureMode {
+			modes = append(modes, mode)
+			return ModeMessage
+		}),
+	)
+
+	logger := slog.New(handler)
+	logger.ErrorContext(ctx, "skipped", "sentry_skip", true)
+	logger.ErrorContext(ctx, "canceled", "err", context.Canceled)
+
+	if n := len(transport.Events()); n != 0 {
+		t.Errorf("expect no events, got: %d", n)
+	}
+	if len(modes) != 0 {
+		t.Errorf("expect the strategy not called, got modes: %v", modes)
+	}
+}
+
+func TestWithCaptureStrategyBreadcrumbData(t *testing.T) {
+	for _, useExtra := range []bool{false, true} {
+		ctx, transport := newTestContext(t, sentry.ClientOptions{})
+		handler := newTestHandler([]slog.Level{slog.LevelInfo, slog.LevelError},
+			WithUseExtra(useExtra),
+			WithMaxBreadcrumbDataSize(150),
+			WithCaptureStrategy(func(_ context.Context, record slog.Record, mode CaptureMode) CaptureMode {
+				if record.Level == slog.LevelInfo {
+					return ModeBreadcrumb
+				}
+				return mode
+			}),
+		)
+
+		logger := slog.New(handler)
+		logger.InfoContext(ctx, "noisy", "a", strings.Repeat("x", 100), "b", strings.Repeat("y", 100))
+		logger.ErrorContext(ctx, "failed")
+
+		events := transport.Events()
+		if len(events) != 1 || len(events[0].Breadcrumbs) != 1 {
+			t.Fatalf("extra %t: expect 1 event with 1 breadcrumb, got: %v", useExtra, events)
+		}
+		if data := events[0].Breadcrumbs[0].Data; data["a"] == nil || data["b"] != nil || data["_trimmed"] != 1 {
+			t.Errorf("extra %t: expect breadcrumb data without b, got: %v", useExtra, data)
+		}
+	}
+}
+
+func TestWithTagBucket(t *testing.T) {
+	tests := []struct {
+		value  slog.Value
+		expect string
+	}{
+		{slog.IntValue(50), "<100"},
+		{slog.IntValue(100), "100-500"},
+		{slog.Float64Value(250), "100-500"},
+		{slog.DurationValue(250 * time.Millisecond), "100-500"},
+		{slog.IntValue(500), ">=500"},
+	}
+
+	for _, test := range tests {
+		record := slog.NewRecord(time.Now(), slog.LevelInfo, "the message", 0)
+		record.AddAttrs(slog.Attr{Key: "latency", Value: test.value})
+		event := CaptureToEvent(context.Background(), record, WithTagBucket("latency", []float64{500, 100}))
+		if event == nil {
+			t.Fatal("expect an event")
+		}
+		if tag := event.Tags["latency"]; tag != test.expect {
+			t.Errorf("expect tag latency %q for %v, got: %q", test.expect, test.value, tag)
+		}
+		if _, ok := event.Contexts["slog"]["latency"]; ok {
+			t.Errorf("expect no latency in the slog context for %v", test.value)
+		}
+	}
+}
+
+func TestWithMaxDepth(t *testing.T) {
+	nested := map[string]any{"a": map[string]any{"b": map[string]any{"c": []int{1}}}}
+	tests := []struct {
+		depth  int
+		expect any
+	}{
+		{0, "<max depth>"},
+		{2, map[string]any{"a": map[string]any{"b": "<max depth>"}}},
+		{4, map[string]any{"a": map[string]any{"b": map[string]any{"c": []any{1}}}}},
+	}
+
+	for _, test := range tests {
+		record := slog.NewRecord(time.Now(), slog.LevelInfo, "the message", 0)
+		record.AddAttrs(slog.Any("nested", nested))
+		event := CaptureToEvent(context.Background(), record, WithMaxDepth(test.depth))
+		if event == nil {
+			t.Fatal("expect an event")
+		}
+		if value := event.Contexts["slog"]["nested"]; !reflect.DeepEqual(value, test.expect) {
+			t.Errorf("depth %d: expect %v, got: %v", test.depth, test.expect, value)
+		}
+	}
+}
+
+func TestWithFlushLevels(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	handler := newTestHandler([]slog.Level{slog.LevelInfo, slog.LevelError}, WithFlushLevels(slog.LevelError))
+
+	logger := slog.New(handler)
+	logger.InfoContext(ctx, "the message")
+	if timeouts := transport.FlushTimeouts(); len(timeouts) != 0 {
+		t.Errorf("expect no flush after an info record, got: %v", timeouts)
+	}
+
+	logger.ErrorContext(ctx, "failed")
+	if timeouts := transport.FlushTimeouts(); !slices.Equal(timeouts, []time.Duration{2 * time.Second}) {
+		t.Errorf("expect a flush after an error record, got: %v", timeouts)
+	}
+	if n := len(transport.Events()); n != 2 {
+		t.Errorf("expect 2 events, got: %d", n)
+	}
+}
+
+func TestWithModifyScope(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	handler := newTestHandler([]slog.Level{slog.LevelError},
+		WithModifyScope(func(scope *sentry.Scope, _ context.Context, record slog.Record) {
+			scope.SetFingerprint([]string{"scoped", record.Message})
+			scope.SetTag("scoped", "yes")
+		}),
+	)
+
+	logger := slog.New(handler)
+	logger.ErrorContext(ctx, "the message")
+	logger.ErrorContext(ctx, "the message", "fingerprint", "record")
+
+	events := transport.Events()
+	if len(events) != 2 {
+		t.Fatalf("expect 2 events, got: %d", len(events))
+	}
+	if expect := []string{"scoped", "the message"}; !slices.Equal(events[0].Fingerprint, expect) {
+		t.Errorf("expect fingerprint %q, got: %q", expect, events[0].Fingerprint)
+	}
+	if tag := events[0].Tags["scoped"]; tag != "yes" {
+		t.Errorf("expect tag scoped %q, got: %q", "yes", tag)
+	}
+	if expect := []string{"record"}; !slices.Equal(events[1].Fingerprint, expect) {
+		t.Errorf("expect record fingerprint %q, got: %q", expect, events[1].Fingerprint)
+	}
+
+	hub := sentry.GetHubFromContext(ctx)
+	hub.CaptureMessage("outside")
+	if events := transport.Events(); len(events[2].Fingerprint) != 0 {
+		t.Errorf("expect the scope of the hub not modified, got fingerprint: %q", events[2].Fingerprint)
+	}
+}
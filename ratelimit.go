@@ -0,0 +1,40 @@
+package slogsentry
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter allows up to max captures per fixed window of time. It is
+// shared by the handlers derived with WithAttrs and WithGroup.
+type rateLimiter struct {
+	max    int
+	window time.Duration
+
+	mu    sync.Mutex
+	start time.Time
+	count int
+}
+
+// allow reports whether a capture at now fits in the current window.
+func (r *rateLimiter) allow(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now.Sub(r.start) >= r.window {
+		r.start = now
+		r.count = 0
+	}
+	if r.count >= r.max {
+		return false
+	}
+	r.count++
+	return true
+}
+
+// reset starts a new window at the next capture.
+func (r *rateLimiter) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.start, r.count = time.Time{}, 0
+}
@@ -0,0 +1,51 @@
+package slogsentry
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// httpRequestKey is the key of the attribute setting the request of an event.
+const httpRequestKey = "http_request"
+
+// scrubbedHeaders are the request headers never sent to Sentry, as they
+// carry credentials.
+var scrubbedHeaders = []string{"Authorization", "Cookie"}
+
+// requestFromAttr returns the Sentry request for a request attribute with
+// an *http.Request or a *sentry.Request value, without the scrubbed headers.
+// It reports false for other attributes.
+func requestFromAttr(attr slog.Attr) (*sentry.Request, bool) {
+	if attr.Key != httpRequestKey || attr.Value.Kind() != slog.KindAny {
+		return nil, false
+	}
+
+	var request sentry.Request
+	switch v := attr.Value.Any().(type) {
+	case *http.Request:
+		if v == nil {
+			return nil, false
+		}
+		request = *sentry.NewRequest(v)
+	case *sentry.Request:
+		if v == nil {
+			return nil, false
+		}
+		request = *v
+	default:
+		return nil, false
+	}
+
+	headers := make(map[string]string, len(request.Headers))
+	for key, value := range request.Headers {
+		if !slices.Contains(scrubbedHeaders, http.CanonicalHeaderKey(key)) {
+			headers[key] = value
+		}
+	}
+	request.Headers = headers
+	request.Cookies = ""
+	return &request, true
+}
@@ -0,0 +1,82 @@
+package slogsentry
+
+import (
+	"context"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+func TestHTTPRequestAttr(t *testing.T) {
+	r := httptest.NewRequest("POST", "http://example.com/orders?id=42", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	r.Header.Set("Cookie", "session=secret")
+	r.Header.Set("User-Agent", "test")
+
+	tests := []struct {
+		name  string
+		value any
+	}{
+		{"http.Request", r},
+		{"sentry.Request", &sentry.Request{
+			URL:     "http://example.com/orders",
+			Method:  "POST",
+			Cookies: "session=secret",
+			Headers: map[string]string{"authorization": "Bearer secret", "User-Agent": "test"},
+		}},
+	}
+
+	for _, test := range tests {
+		record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+		record.AddAttrs(slog.Any("http_request", test.value))
+
+		event := CaptureToEvent(context.Background(), record)
+		if event == nil {
+			t.Fatalf("%s: expect an event", test.name)
+		}
+		request := event.Request
+		if request == nil {
+			t.Fatalf("%s: expect a request", test.name)
+		}
+		if request.URL != "http://example.com/orders" || request.Method != "POST" {
+			t.Errorf("%s: expect POST http://example.com/orders, got: %s %s", test.name, request.Method, request.URL)
+		}
+		if ua := request.Headers["User-Agent"]; ua != "test" {
+			t.Errorf("%s: expect header User-Agent %q, got: %q", test.name, "test", ua)
+		}
+		for key := range request.Headers {
+			if key == "Authorization" || key == "authorization" || key == "Cookie" {
+				t.Errorf("%s: expect header %s scrubbed", test.name, key)
+			}
+		}
+		if request.Cookies != "" {
+			t.Errorf("%s: expect cookies scrubbed, got: %q", test.name, request.Cookies)
+		}
+		if _, ok := event.Contexts["slog"]["http_request"]; ok {
+			t.Errorf("%s: expect no http_request context", test.name)
+		}
+	}
+
+	if request := tests[1].value.(*sentry.Request); request.Headers["authorization"] == "" {
+		t.Error("expect the logged sentry.Request not to be modified")
+	}
+}
+
+func TestHTTPRequestAttrOtherValue(t *testing.T) {
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	record.AddAttrs(slog.String("http_request", "GET /"))
+
+	event := CaptureToEvent(context.Background(), record)
+	if event == nil {
+		t.Fatal("expect an event")
+	}
+	if event.Request != nil {
+		t.Errorf("expect no request, got: %+v", event.Request)
+	}
+	if value := event.Contexts["slog"]["http_request"]; value != "GET /" {
+		t.Errorf("expect context http_request %q, got: %v", "GET /", value)
+	}
+}
@@ -0,0 +1,59 @@
+package slogsentry
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// packagePath is the import path of this package.
+var packagePath = reflect.TypeOf(SentryHandler{}).PkgPath()
+
+// sourceFrame returns the frame of the program counter of a record.
+func sourceFrame(pc uintptr) runtime.Frame {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	return frame
+}
+
+// trimEventFrames is a sentry.EventProcessor that trims the handler frames
+// from the stack traces attached to event, so that the innermost frame is
+// the code that logged.
+func trimEventFrames(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+	for i := range event.Exception {
+		if event.Exception[i].Stacktrace != nil {
+			event.Exception[i].Stacktrace.Frames = trimFrames(event.Exception[i].Stacktrace.Frames)
+		}
+	}
+	for i := range event.Threads {
+		if event.Threads[i].Stacktrace != nil {
+			event.Threads[i].Stacktrace.Frames = trimFrames(event.Threads[i].Stacktrace.Frames)
+		}
+	}
+	return event
+}
+
+// trimFrames drops the innermost frames belonging to log/slog or the handler,
+// and the log frames calling log/slog, e.g. for a logger of NewStdLogger.
+// Sentry orders frames from outermost to innermost.
+func trimFrames(frames []sentry.Frame) []sentry.Frame {
+	slogFrames := false
+	for len(frames) > 0 && isHandlerFrame(frames[len(frames)-1]) {
+		slogFrames = slogFrames || frames[len(frames)-1].Module != packagePath
+		frames = frames[:len(frames)-1]
+	}
+	for slogFrames && len(frames) > 0 && frames[len(frames)-1].Module == "log" {
+		frames = frames[:len(frames)-1]
+	}
+	return frames
+}
+
+// isHandlerFrame reports whether frame is inside log/slog or this package.
+// Like Sentry does for its own frames, frames from test files are kept.
+func isHandlerFrame(frame sentry.Frame) bool {
+	if frame.Module == "log/slog" || strings.HasPrefix(frame.Module, "log/slog/") {
+		return true
+	}
+	return frame.Module == packagePath && !strings.HasSuffix(frame.AbsPath, "_test.go")
+}
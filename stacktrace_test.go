@@ -0,0 +1,68 @@
+package slogsentry
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+)
+
+func TestTrimFrames(t *testing.T) {
+	frames := []sentry.Frame{
+		{Module: "main", Function: "main"},
+		{Module: "log/slog", Function: "(*Logger).Error"},
+		{Module: packagePath, Function: "(*SentryHandler).Handle", AbsPath: "/src/handler.go"},
+	}
+
+	trimmed := trimFrames(frames)
+	if len(trimmed) != 1 || trimmed[0].Function != "main" {
+		t.Errorf("expect only the main frame, got: %+v", trimmed)
+	}
+
+	frames = []sentry.Frame{
+		{Module: "main", Function: "main"},
+		{Module: "log", Function: "(*Logger).Printf"},
+		{Module: "log", Function: "(*Logger).output"},
+		{Module: "log/slog", Function: "(*handlerWriter).Write"},
+		{Module: packagePath, Function: "(*SentryHandler).Handle", AbsPath: "/src/handler.go"},
+	}
+	trimmed = trimFrames(frames)
+	if len(trimmed) != 1 || trimmed[0].Function != "main" {
+		t.Errorf("expect only the main frame, got: %+v", trimmed)
+	}
+
+	// Only log frames calling log/slog are trimmed.
+	frames = []sentry.Frame{
+		{Module: "main", Function: "main"},
+		{Module: "log", Function: "(*Logger).Printf"},
+		{Module: packagePath, Function: "(*SentryHandler).Handle", AbsPath: "/src/handler.go"},
+	}
+	trimmed = trimFrames(frames)
+	if len(trimmed) != 2 || trimmed[1].Function != "(*Logger).Printf" {
+		t.Errorf("expect the main and log frames, got: %+v", trimmed)
+	}
+}
+
+func TestExceptionStacktraceStartsAtCaller(t *testing.T) {
+	ctx, transport := newTestContext(t, sentry.ClientOptions{})
+	logger := slog.New(newTestHandler([]slog.Level{slog.LevelError}))
+
+	logger.ErrorContext(ctx, "the message", "err", errors.New("the error"))
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("expect 1 event, got: %d", len(events))
+	}
+	exceptions := events[0].Exception
+	if len(exceptions) == 0 || exceptions[len(exceptions)-1].Stacktrace == nil {
+		t.Fatalf("expect an exception with a stacktrace, got: %+v", exceptions)
+	}
+	frames := exceptions[len(exceptions)-1].Stacktrace.Frames
+	if len(frames) == 0 {
+		t.Fatal("expect stacktrace frames")
+	}
+	if top := frames[len(frames)-1]; top.Function != "TestExceptionStacktraceStartsAtCaller" {
+		t.Errorf("expect top frame %q, got: %q in %q", "TestExceptionStacktraceStartsAtCaller", top.Function, top.Module)
+	}
+}
@@ -0,0 +1,15 @@
+package slogsentry
+
+import (
+	"log"
+	"log/slog"
+)
+
+// NewStdLogger returns a log.Logger writing to handler at level, e.g. a
+// SentryHandler, so that legacy log.Printf calls, or libraries taking a
+// *log.Logger, are captured too. Like slog.NewLogLogger, it logs without a
+// context, so records are captured with the hub set with WithLevelHub, or
+// the current hub.
+func NewStdLogger(handler slog.Handler, level slog.Level) *log.Logger {
+	return slog.NewLogLogger(handler, level)
+}
@@ -0,0 +1,34 @@
+package slogsentry
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+)
+
+func TestNewStdLogger(t *testing.T) {
+	hub, transport := newTestHub(t, sentry.ClientOptions{})
+	handler := newTestHandler([]slog.Level{slog.LevelWarn}, WithLevelHub(slog.LevelWarn, hub), WithAttachStacktrace(true))
+
+	logger := NewStdLogger(handler, slog.LevelWarn)
+	logger.Printf("legacy %s", "warning")
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("expect 1 event, got: %d", len(events))
+	}
+	if events[0].Message != "legacy warning" || events[0].Level != sentry.LevelInfo {
+		t.Errorf("expect info message %q, got: %s message %q", "legacy warning", events[0].Level, events[0].Message)
+	}
+	if len(events[0].Threads) != 1 || events[0].Threads[0].Stacktrace == nil {
+		t.Fatalf("expect a thread with a stacktrace, got: %+v", events[0].Threads)
+	}
+	frames := events[0].Threads[0].Stacktrace.Frames
+	if len(frames) == 0 {
+		t.Fatal("expect stacktrace frames")
+	}
+	if top := frames[len(frames)-1]; top.Function != "TestNewStdLogger" {
+		t.Errorf("expect top frame %q, got: %q in %q", "TestNewStdLogger", top.Function, top.Module)
+	}
+}
@@ -0,0 +1,59 @@
+package slogsentry
+
+import (
+	"encoding/json"
+	"slices"
+)
+
+// trimmedKey is the key of the number of entries dropped from data trimmed
+// to its maximum size.
+const trimmedKey = "_trimmed"
+
+// trimData returns data without the entries exceeding max bytes of JSON,
+// dropping the entries with the last keys in sorted order first, and the
+// number of dropped entries under trimmedKey. It returns data itself when
+// max is 0 or less, or data fits.
+func trimData(data map[string]any, max int) map[string]any {
+	if max <= 0 || len(data) == 0 {
+		return data
+	}
+
+	keys := make([]string, 0, len(data))
+	sizes := make(map[string]int, len(data))
+	size := len("{}")
+	for key, value := range data {
+		keys = append(keys, key)
+		sizes[key] = entrySize(key, value)
+		size += sizes[key]
+	}
+	if size <= max {
+		return data
+	}
+	slices.Sort(keys)
+
+	// Reserve room for the marker of the dropped entries.
+	size += entrySize(trimmedKey, len(keys))
+	for len(keys) > 0 && size > max {
+		size -= sizes[keys[len(keys)-1]]
+		keys = keys[:len(keys)-1]
+	}
+
+	trimmed := make(map[string]any, len(keys)+1)
+	for _, key := range keys {
+		trimmed[key] = data[key]
+	}
+	trimmed[trimmedKey] = len(data) - len(keys)
+	return trimmed
+}
+
+// entrySize returns the size in bytes of the JSON object entry of key and
+// value, including its separator. A value that cannot be encoded counts as
+// null.
+func entrySize(key string, value any) int {
+	encodedKey, _ := json.Marshal(key)
+	encodedValue, err := json.Marshal(value)
+	if err != nil {
+		encodedValue = []byte("null")
+	}
+	return len(encodedKey) + len(":") + len(encodedValue) + len(",")
+}
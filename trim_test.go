@@ -0,0 +1,39 @@
+package slogsentry
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTrimData(t *testing.T) {
+	data := map[string]any{
+		"a": strings.Repeat("x", 20),
+		"b": strings.Repeat("y", 20),
+		"c": strings.Repeat("z", 20),
+	}
+
+	if trimmed := trimData(data, 0); !reflect.DeepEqual(trimmed, data) {
+		t.Errorf("expect data without a limit, got: %v", trimmed)
+	}
+	if trimmed := trimData(data, 1000); !reflect.DeepEqual(trimmed, data) {
+		t.Errorf("expect data fitting the limit, got: %v", trimmed)
+	}
+
+	trimmed := trimData(data, 60)
+	expected := map[string]any{"a": data["a"], trimmedKey: 2}
+	if !reflect.DeepEqual(trimmed, expected) {
+		t.Errorf("expect %v, got: %v", expected, trimmed)
+	}
+	if encoded, _ := json.Marshal(trimmed); len(encoded) > 60 {
+		t.Errorf("expect at most 60 bytes, got: %d", len(encoded))
+	}
+	if len(data) != 3 {
+		t.Errorf("expect data not modified, got: %v", data)
+	}
+
+	if trimmed := trimData(data, 5); !reflect.DeepEqual(trimmed, map[string]any{trimmedKey: 3}) {
+		t.Errorf("expect all entries dropped, got: %v", trimmed)
+	}
+}
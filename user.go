@@ -0,0 +1,40 @@
+package slogsentry
+
+import "github.com/getsentry/sentry-go"
+
+// setUserField sets the field of user named by the key of a user attribute
+// without its prefix, e.g. "id" or "email". Other names are added to the
+// user data.
+func setUserField(user *sentry.User, name, value string) {
+	switch name {
+	case "id":
+		user.ID = value
+	case "email":
+		user.Email = value
+	case "ip_address":
+		user.IPAddress = value
+	case "username":
+		user.Username = value
+	case "name":
+		user.Name = value
+	case "segment":
+		user.Segment = value
+	default:
+		if user.Data == nil {
+			user.Data = map[string]string{}
+		}
+		user.Data[name] = value
+	}
+}
+
+// copyUser returns a copy of user that does not share its data.
+func copyUser(user sentry.User) sentry.User {
+	if user.Data != nil {
+		data := make(map[string]string, len(user.Data))
+		for key, value := range user.Data {
+			data[key] = value
+		}
+		user.Data = data
+	}
+	return user
+}
@@ -0,0 +1,36 @@
+package slogsentry
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+func TestUserAttrs(t *testing.T) {
+	record := slog.NewRecord(time.Now(), slog.LevelError, "the message", 0)
+	record.AddAttrs(
+		slog.String("user_id", "7"),
+		slog.String("user_email", "bob@example.com"),
+		slog.String("user_plan", "gold"),
+	)
+
+	event := CaptureToEvent(context.Background(), record, WithUserPrefix("user_"))
+	if event == nil {
+		t.Fatal("expect an event")
+	}
+	expect := sentry.User{ID: "7", Email: "bob@example.com", Data: map[string]string{"plan": "gold"}}
+	if !reflect.DeepEqual(event.User, expect) {
+		t.Errorf("expect user: %+v, got: %+v", expect, event.User)
+	}
+	if _, ok := event.Contexts["slog"]["user_id"]; ok {
+		t.Error("expect user attributes not in context")
+	}
+
+	if event := CaptureToEvent(context.Background(), record); !event.User.IsEmpty() {
+		t.Errorf("expect no user without a user prefix, got: %+v", event.User)
+	}
+}
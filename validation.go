@@ -0,0 +1,24 @@
+package slogsentry
+
+import "log/slog"
+
+const (
+	// validationErrorsKey is the key of the attribute with the validation
+	// errors of a record, a map[string]string of field to message.
+	validationErrorsKey = "validation_errors"
+	// validationSection is the context section of the validation errors.
+	validationSection = "validation"
+	// validationTag is the tag marking events with validation errors.
+	validationTag = "validation"
+)
+
+// validationErrorsFromAttr returns the validation errors of a validation
+// errors attribute with a map[string]string value. It reports false for
+// other attributes.
+func validationErrorsFromAttr(attr slog.Attr) (map[string]string, bool) {
+	if attr.Key != validationErrorsKey || attr.Value.Kind() != slog.KindAny {
+		return nil, false
+	}
+	errs, ok := attr.Value.Any().(map[string]string)
+	return errs, ok
+}
@@ -0,0 +1,44 @@
+package slogsentry
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+func TestValidationErrorsAttr(t *testing.T) {
+	record := slog.NewRecord(time.Now(), slog.LevelWarn, "invalid order", 0)
+	record.AddAttrs(slog.Any("validation_errors", map[string]string{
+		"email":    "is not an email address",
+		"quantity": "must be positive",
+	}))
+
+	event := CaptureToEvent(context.Background(), record)
+	if event == nil {
+		t.Fatal("expect an event")
+	}
+	expected := sentry.Context{"email": "is not an email address", "quantity": "must be positive"}
+	if section := event.Contexts["validation"]; !reflect.DeepEqual(section, expected) {
+		t.Errorf("expect validation context %v, got: %v", expected, section)
+	}
+	if tag := event.Tags["validation"]; tag != "true" {
+		t.Errorf("expect tag validation %q, got: %q", "true", tag)
+	}
+	if _, ok := event.Contexts["slog"]["validation_errors"]; ok {
+		t.Error("expect no validation_errors in the slog context")
+	}
+
+	record = slog.NewRecord(time.Now(), slog.LevelWarn, "invalid order", 0)
+	record.AddAttrs(slog.String("validation_errors", "email"))
+	event = CaptureToEvent(context.Background(), record)
+	if _, ok := event.Contexts["validation"]; ok {
+		t.Error("expect no validation context for other values")
+	}
+	if value := event.Contexts["slog"]["validation_errors"]; value != "email" {
+		t.Errorf("expect context validation_errors %q, got: %v", "email", value)
+	}
+}
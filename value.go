@@ -0,0 +1,205 @@
+package slogsentry
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"unicode/utf8"
+)
+
+const (
+	// defaultMaxDepth is the default maximum nesting of maps and slices in
+	// the context.
+	defaultMaxDepth = 5
+
+	// defaultMaxValueLength is the default maximum length in bytes of the
+	// strings in the context and tags.
+	defaultMaxValueLength = 8192
+
+	maxDepthValue  = "<max depth>"
+	truncateSuffix = "..."
+
+	// maxLogValues is the maximum number of LogValue calls resolve makes,
+	// like slog's own limit.
+	maxLogValues = 100
+)
+
+// logValuePanic is the value of an attribute whose LogValue panicked.
+type logValuePanic struct {
+	recovered any
+}
+
+func (p logValuePanic) String() string {
+	return fmt.Sprintf("<LogValue panicked: %v>", p.recovered)
+}
+
+// resolve resolves value like slog.Value.Resolve, but returns a
+// logValuePanic value when LogValue panics, rather than an error value, so
+// that the handler can report the attribute. Slog's own Resolve recovers the
+// panic itself, hence the calls to LogValue here. Like Resolve, it returns
+// an error value after maxLogValues calls, so that a LogValuer returning
+// itself does not recurse forever.
+func resolve(value slog.Value) (resolved slog.Value) {
+	defer func() {
+		if r := recover(); r != nil {
+			resolved = slog.AnyValue(logValuePanic{recovered: r})
+		}
+	}()
+	for i := 0; i < maxLogValues && value.Kind() == slog.KindLogValuer; i++ {
+		value = value.LogValuer().LogValue()
+	}
+	if value.Kind() == slog.KindLogValuer {
+		return slog.AnyValue(fmt.Errorf("LogValue called too many times on Value of type %T", value.Any()))
+	}
+	return value
+}
+
+// contextValue returns the value of an attribute in the context. Maps,
+// slices and JSON become structures Sentry renders as objects, other values
+// strings.
+func (s *SentryHandler) contextValue(value slog.Value) any {
+	return s.nestedValue(value, 0)
+}
+
+// nestedValue returns the context value of value nested depth levels deep.
+func (s *SentryHandler) nestedValue(value slog.Value, depth int) any {
+	if value.Kind() != slog.KindAny {
+		return s.stringValue(value)
+	}
+	if v, ok := s.jsonValue(value.Any()); ok {
+		return s.normalize(v, depth)
+	}
+	if isStructured(reflect.ValueOf(value.Any())) {
+		return s.normalize(value.Any(), depth)
+	}
+	return s.stringValue(value)
+}
+
+// groupValue returns the context value of a group attribute in groups: a map
+// of its members, prepared like the attributes of the record, so that
+// WithReplaceAttr sees them with the group path like the wrapped handler
+// does. Members of groups with an empty key are inlined.
+func (s *SentryHandler) groupValue(groups []string, attr slog.Attr, depth int) any {
+	if depth >= s.maxDepth {
+		return maxDepthValue
+	}
+	m := map[string]any{}
+	s.addGroupMembers(m, append(slices.Clip(groups), attr.Key), attr.Value.Group(), depth)
+	return m
+}
+
+func (s *SentryHandler) addGroupMembers(m map[string]any, groups []string, members []slog.Attr, depth int) {
+	for _, member := range members {
+		if member.Key == "" {
+			if value := resolve(member.Value); value.Kind() == slog.KindGroup {
+				s.addGroupMembers(m, groups, value.Group(), depth)
+				continue
+			}
+		}
+		member, ok := s.prepareAttr(groups, member)
+		if !ok {
+			continue
+		}
+		if member.Value.Kind() == slog.KindGroup {
+			m[member.Key] = s.groupValue(groups, member, depth+1)
+		} else {
+			m[member.Key] = s.nestedValue(member.Value, depth+1)
+		}
+	}
+}
+
+// jsonValue returns v unmarshaled when it is a json.RawMessage, or a []byte
+// with WithJSONBytes, holding valid JSON.
+func (s *SentryHandler) jsonValue(v any) (any, bool) {
+	var data []byte
+	switch v := v.(type) {
+	case json.RawMessage:
+		data = v
+	case []byte:
+		if !s.jsonBytes {
+			return nil, false
+		}
+		data = v
+	default:
+		return nil, false
+	}
+
+	var unmarshaled any
+	if err := json.Unmarshal(data, &unmarshaled); err != nil {
+		return nil, false
+	}
+	return unmarshaled, true
+}
+
+// stringValue returns value as a string of at most the maximum length.
+func (s *SentryHandler) stringValue(value slog.Value) string {
+	return truncate(value.String(), s.maxValueLength)
+}
+
+// isStructured reports whether v is a map or a slice, other than []byte.
+func isStructured(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Map:
+		return true
+	case reflect.Slice, reflect.Array:
+		return v.Type().Elem().Kind() != reflect.Uint8
+	}
+	return false
+}
+
+// normalize converts maps and slices in v into map[string]any and []any, up
+// to the maximum depth, which also guards against cycles. JSON
+// compatible values are kept, others are formatted with fmt.
+func (s *SentryHandler) normalize(v any, depth int) any {
+	switch v := v.(type) {
+	case nil, bool,
+		int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64,
+		float32, float64:
+		return v
+	case string:
+		return truncate(v, s.maxValueLength)
+	}
+
+	rv := reflect.ValueOf(v)
+	if !isStructured(rv) {
+		return truncate(fmt.Sprint(v), s.maxValueLength)
+	}
+	if depth >= s.maxDepth {
+		return maxDepthValue
+	}
+
+	if rv.Kind() == reflect.Map {
+		m := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			key := truncate(fmt.Sprint(iter.Key().Interface()), s.maxValueLength)
+			m[key] = s.normalize(iter.Value().Interface(), depth+1)
+		}
+		return m
+	}
+
+	l := make([]any, rv.Len())
+	for i := range l {
+		l[i] = s.normalize(rv.Index(i).Interface(), depth+1)
+	}
+	return l
+}
+
+// truncate shortens str to at most max bytes, without splitting a UTF-8
+// encoded rune, and marks it as truncated. A max of 0 or less is no limit.
+func truncate(str string, max int) string {
+	if max <= 0 || len(str) <= max {
+		return str
+	}
+	cut := max - len(truncateSuffix)
+	if cut < 0 {
+		cut = 0
+	}
+	for cut > 0 && !utf8.RuneStart(str[cut]) {
+		cut--
+	}
+	return str[:cut] + truncateSuffix
+}
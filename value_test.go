@@ -0,0 +1,112 @@
+package slogsentry
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestContextValue(t *testing.T) {
+	cyclic := map[string]any{}
+	cyclic["self"] = cyclic
+
+	tests := []struct {
+		input  slog.Value
+		expect any
+	}{
+		{slog.StringValue("text"), "text"},
+		{slog.IntValue(42), "42"},
+		{slog.AnyValue([]byte("hi")), "[104 105]"},
+		{slog.AnyValue([]int{1, 2}), []any{1, 2}},
+		{
+			slog.AnyValue(map[string]any{"user": map[string]any{"id": 7, "roles": []string{"admin"}}}),
+			map[string]any{"user": map[string]any{"id": 7, "roles": []any{"admin"}}},
+		},
+		{slog.AnyValue(map[int]struct{ A int }{1: {A: 2}}), map[string]any{"1": "{2}"}},
+		{
+			slog.AnyValue(cyclic),
+			map[string]any{"self": map[string]any{"self": map[string]any{"self": map[string]any{"self": map[string]any{"self": "<max depth>"}}}}},
+		},
+	}
+
+	for i, test := range tests {
+		output := newTestHandler(nil).contextValue(test.input)
+		if !reflect.DeepEqual(output, test.expect) {
+			t.Errorf("test %d: expect: %#v, got: %#v", i, test.expect, output)
+		}
+	}
+}
+
+func TestContextValueJSON(t *testing.T) {
+	payload := `{"order": {"id": 42, "items": ["a", "b"]}, "paid": true}`
+	structured := map[string]any{"order": map[string]any{"id": float64(42), "items": []any{"a", "b"}}, "paid": true}
+
+	tests := []struct {
+		jsonBytes bool
+		input     slog.Value
+		expect    any
+	}{
+		{false, slog.AnyValue(json.RawMessage(payload)), structured},
+		{false, slog.AnyValue(json.RawMessage(`"text"`)), "text"},
+		{false, slog.AnyValue(json.RawMessage(`{invalid`)), fmt.Sprint(json.RawMessage(`{invalid`))},
+		{false, slog.AnyValue([]byte(payload)), fmt.Sprint([]byte(payload))},
+		{true, slog.AnyValue([]byte(payload)), structured},
+		{true, slog.AnyValue([]byte("hi")), "[104 105]"},
+	}
+
+	for i, test := range tests {
+		output := newTestHandler(nil, WithJSONBytes(test.jsonBytes)).contextValue(test.input)
+		if !reflect.DeepEqual(output, test.expect) {
+			t.Errorf("test %d: expect: %#v, got: %#v", i, test.expect, output)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		input  string
+		max    int
+		expect string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"much too long", 10, "much to..."},
+		{"héllo wörld", 6, "hé..."},
+		{"héllo wörld", 5, "h..."},
+		{"anything", 0, "anything"},
+		{"long", 2, "..."},
+	}
+
+	for i, test := range tests {
+		if output := truncate(test.input, test.max); output != test.expect {
+			t.Errorf("test %d: expect: %q, got: %q", i, test.expect, output)
+		}
+	}
+}
+
+// countingValuer is a slog.LogValuer that resolves to itself, counting the
+// calls.
+type countingValuer struct {
+	calls *int
+}
+
+func (v countingValuer) LogValue() slog.Value {
+	*v.calls++
+	return slog.AnyValue(v)
+}
+
+func TestResolveBoundsLogValueCalls(t *testing.T) {
+	var calls int
+	value := resolve(slog.AnyValue(countingValuer{calls: &calls}))
+	if calls != maxLogValues {
+		t.Errorf("expect %d LogValue calls, got: %d", maxLogValues, calls)
+	}
+	if err, ok := value.Any().(error); !ok || value.Kind() == slog.KindLogValuer {
+		t.Errorf("expect an error value, got: %v", value)
+	} else if expect := "LogValue called too many times"; !strings.Contains(err.Error(), expect) {
+		t.Errorf("expect error %q, got: %q", expect, err)
+	}
+}
@@ -0,0 +1,33 @@
+package slogsentry
+
+import "sync"
+
+// nilHubMessage is the message of the warning that records are not sent to
+// Sentry for lack of a hub.
+const nilHubMessage = "slogsentry: the sentry hub is nil, records are not sent to Sentry"
+
+// onceWarning runs a warning once until reset. It is shared by the handlers
+// derived with WithAttrs and WithGroup.
+type onceWarning struct {
+	mu   sync.Mutex
+	once *sync.Once
+}
+
+func newOnceWarning() *onceWarning {
+	return &onceWarning{once: &sync.Once{}}
+}
+
+// do calls warn, unless it was called since the last reset.
+func (w *onceWarning) do(warn func()) {
+	w.mu.Lock()
+	once := w.once
+	w.mu.Unlock()
+	once.Do(warn)
+}
+
+// reset makes the next do call warn again.
+func (w *onceWarning) reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.once = &sync.Once{}
+}